@@ -6,6 +6,7 @@ import (
 	"os"
 	"os/exec"
 	"os/signal"
+	"path/filepath"
 	"strings"
 	"syscall"
 	"time"
@@ -18,15 +19,19 @@ import (
 )
 
 var (
-	importGif    = flag.String("import-gif", "", "Import GIF file (URL or local path)")
+	importGif    = flag.String("import-gif", "", "Import GIF or image file (URL or local path), like --import")
+	importFile   = flag.String("import", "", "Import a file by its extension: .gif, .png, .jpg, .bmp (URL or path), .cast (asciinema recording), .ans (ANSI art), .txt (text frames)")
 	outputFile   = flag.String("output", "", "Output file (default: opens in editor)")
 	width        = flag.Int("width", 0, "Canvas width for import (0 = auto-detect from terminal)")
 	height       = flag.Int("height", 0, "Canvas height for import (0 = auto-detect from terminal)")
 	fps          = flag.Int("fps", 12, "Target FPS for imported animation")
-	method       = flag.String("method", "luminosity", "Conversion method: luminosity, average, block, dither")
+	method       = flag.String("method", "luminosity", "Conversion method: luminosity, average, block, dither, braille, halfblock, quadrant")
+	threshold    = flag.Int("threshold", 0, "Braille: luminosity 1-255 a pixel needs to become a dot (0 = the image's mean)")
+	dither       = flag.String("dither", "", "Dithering: none, floyd-steinberg, atkinson, bayer4 (ordered), bayer8")
 	ratio        = flag.String("ratio", "fill", "Aspect ratio handling: fill, fit, original")
 	chars        = flag.String("chars", "", "Custom character set for conversion (default: auto)")
 	useColors    = flag.Bool("colors", false, "Use RGB colors (default: monochrome grayscale)")
+	durations    = flag.String("durations", "", "Sidecar file of per-image durations for image sequence imports")
 	showHelp     = flag.Bool("help", false, "Show help message")
 	version      = flag.Bool("version", false, "Show version")
 	initConfig   = flag.Bool("init", false, "Initialize configuration directory")
@@ -35,17 +40,30 @@ var (
 	rawMode      = flag.Bool("raw", false, "Raw playback mode (no UI, just animation)")
 	centerMode   = flag.Bool("center", false, "Center the animation in terminal (works with --raw)")
 	onceMode     = flag.Bool("once", false, "Play animation once then exit (works with --raw)")
+	keyframeInterval = flag.Int("keyframe-interval", -1, "Store frames between keyframes as deltas, a keyframe every N frames (0 = all keyframes, -1 = config)")
 	
 	// Export options
 	exportFile   = flag.String("export", "", "Export file to format (specify output path)")
-	exportFormat = flag.String("export-format", "json", "Export format: json, csv, ansi, txt, html, svg")
+	exportFormat = flag.String("export-format", "json", "Export format: json, csv, ansi, txt, html, svg, gif, png, spritesheet, asciicast, bbs, go, frames")
 	exportFrame  = flag.Int("export-frame", -1, "Export specific frame (-1 for all)")
 	exportColors = flag.Bool("export-colors", true, "Include colors in export")
+	colorMode    = flag.String("color-mode", "truecolor", "Colors of ANSI and asciicast exports and of --colors imports: truecolor, 256, 16, none")
+	cellSize     = flag.String("cell-size", "", "Pixel size of a cell for image exports, WxH (default 12x20)")
+	sheetColumns = flag.Int("sheet-columns", 0, "Frames per row of a sprite sheet (0 = square grid)")
+	goPackage    = flag.String("go-package", "main", "Package name of a Go export")
+	goVar        = flag.String("go-var", "", "Variable name of a Go export (default: from the output file name)")
+	frameDelim   = flag.String("frame-delimiter", fileformat.DefaultFrameDelimiter, "Line between frames of text frame exports and imports")
+	frameDurations = flag.Bool("frame-durations", false, "Start every frame of a text frame export with its duration")
 )
 
 const versionString = "aart v0.1.0"
 
 func main() {
+	// Subcommands have their own flags
+	if len(os.Args) > 1 && os.Args[1] == "validate" {
+		os.Exit(runValidate(os.Args[2:]))
+	}
+
 	flag.Parse()
 	
 	// Track which flags were explicitly set by the user
@@ -103,6 +121,9 @@ func main() {
 		fmt.Fprintf(os.Stderr, "Warning: Failed to load config, using defaults: %v\n", err)
 		cfg = &config.DefaultConfig
 	}
+	if *keyframeInterval >= 0 {
+		cfg.KeyframeIntervalOverride = keyframeInterval
+	}
 
 	// Handle export
 	if *exportFile != "" {
@@ -121,13 +142,31 @@ func main() {
 
 	// Handle GIF import
 	if *importGif != "" {
-		if err := handleGifImport(cfg, flagsSet); err != nil {
+		if err := handleGifImport(cfg, *importGif, flagsSet); err != nil {
 			fmt.Fprintf(os.Stderr, "Error importing GIF: %v\n", err)
 			os.Exit(1)
 		}
 		return
 	}
 
+	// Images and image sequences go through the converter like GIFs
+	if *importFile != "" && (converter.IsImageSource(*importFile) || converter.IsSequenceSource(*importFile)) {
+		if err := handleGifImport(cfg, *importFile, flagsSet); err != nil {
+			fmt.Fprintf(os.Stderr, "Error importing image: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Handle other imports
+	if *importFile != "" {
+		if err := handleImport(cfg); err != nil {
+			fmt.Fprintf(os.Stderr, "Error importing: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	// Start interactive editor
 	var model tea.Model
 	
@@ -143,21 +182,26 @@ func main() {
 			os.Exit(1)
 		}
 		
-		// Load the .aa file
-		aartFile, err := fileformat.Load(filepath)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error loading file: %v\n", err)
-			os.Exit(1)
-		}
-		
-		// Raw mode: just play the animation without UI
+		// Raw mode: just play the animation without UI. Packed files
+		// are decoded frame by frame, here and in the editor.
 		if *rawMode {
-			playRawAnimation(aartFile)
+			src, err := fileformat.Open(filepath)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error loading file: %v\n", err)
+				os.Exit(1)
+			}
+			playRawAnimation(src)
+			src.Close()
 			return
 		}
 		
-		// Create editor model with loaded file
-		model = ui.NewWithFile(cfg, filepath, aartFile)
+		// Create editor model with the file
+		editor, err := ui.OpenFile(cfg, filepath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading file: %v\n", err)
+			os.Exit(1)
+		}
+		model = editor
 	} else {
 		// Show startup page
 		model = ui.NewStartupPage(cfg)
@@ -175,9 +219,11 @@ func main() {
 	}
 }
 
-// playRawAnimation plays animation in raw mode (no UI, just frames)
-func playRawAnimation(aartFile *fileformat.AartFile) {
-	if len(aartFile.Frames) == 0 {
+// playRawAnimation plays animation in raw mode (no UI, just frames).
+// Frames are read from src as they are shown, so a packed file is decoded
+// one frame at a time.
+func playRawAnimation(src fileformat.FrameSource) {
+	if src.FrameCount() == 0 {
 		return
 	}
 	
@@ -186,7 +232,7 @@ func playRawAnimation(aartFile *fileformat.AartFile) {
 	defer fmt.Print("\033[?25h") // Show cursor on exit
 	
 	// Calculate frame delay from first frame duration
-	frameDuration := time.Duration(aartFile.Frames[0].Duration) * time.Millisecond
+	frameDuration := time.Duration(src.Header().Frames[0].Duration) * time.Millisecond
 	if frameDuration == 0 {
 		frameDuration = 83 * time.Millisecond // Default ~12fps
 	}
@@ -222,7 +268,11 @@ func playRawAnimation(aartFile *fileformat.AartFile) {
 			fmt.Print("\033[H")
 			
 			// Render current frame
-			frame := aartFile.Frames[frameIdx]
+			frame, err := src.Frame(frameIdx)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "\nError reading frame %d: %v\n", frameIdx+1, err)
+				return
+			}
 			
 			if *centerMode {
 				// Calculate padding for centering
@@ -305,7 +355,7 @@ func playRawAnimation(aartFile *fileformat.AartFile) {
 			}
 			
 			// Next frame
-			frameIdx = (frameIdx + 1) % len(aartFile.Frames)
+			frameIdx = (frameIdx + 1) % src.FrameCount()
 			
 			// Check if we completed a loop
 			if frameIdx == 0 {
@@ -332,7 +382,9 @@ func hexToRGB(hex string) [3]int {
 	return [3]int{r, g, b}
 }
 
-func handleGifImport(cfg *config.Config, flagsSet map[string]bool) error {
+// handleGifImport converts an animated GIF or a still image with the
+// converter, then saves it, plays it or opens it in the editor
+func handleGifImport(cfg *config.Config, source string, flagsSet map[string]bool) error {
 	// Auto-detect terminal size if width/height not specified
 	convertWidth := *width
 	convertHeight := *height
@@ -367,8 +419,16 @@ func handleGifImport(cfg *config.Config, flagsSet map[string]bool) error {
 		convertMethod = cfg.Converter.DefaultMethod
 	}
 	
-	fmt.Printf("🎨 aart - GIF to ASCII Converter\n\n")
-	fmt.Printf("Source: %s\n", *importGif)
+	if _, err := converter.ParseDither(*dither); err != nil {
+		return err
+	}
+	palette, err := fileformat.ParseColorMode(*colorMode)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("🎨 aart - Image to ASCII Converter\n\n")
+	fmt.Printf("Source: %s\n", source)
 	fmt.Printf("Target: %dx%d @ %dfps\n", convertWidth, convertHeight, convertFPS)
 	fmt.Printf("Method: %s\n", convertMethod)
 	fmt.Printf("Ratio: %s\n", convertRatio)
@@ -391,7 +451,7 @@ func handleGifImport(cfg *config.Config, flagsSet map[string]bool) error {
 		}
 	}
 
-	frames, err := converter.ConvertGifToFrames(*importGif, converter.Options{
+	frames, err := converter.ConvertToFrames(source, converter.Options{
 		Width:            convertWidth,
 		Height:           convertHeight,
 		FPS:              convertFPS,
@@ -399,6 +459,10 @@ func handleGifImport(cfg *config.Config, flagsSet map[string]bool) error {
 		Ratio:            convertRatio,
 		Chars:            *chars,
 		UseColors:        *useColors,
+		Palette:          palette,
+		Threshold:        *threshold,
+		Dither:           *dither,
+		Durations:        *durations,
 		ProgressCallback: progressCallback,
 	})
 	if err != nil {
@@ -410,7 +474,8 @@ func handleGifImport(cfg *config.Config, flagsSet map[string]bool) error {
 	// If output file specified, save it
 	if *outputFile != "" {
 		fmt.Printf("💾 Saving to %s...\n", *outputFile)
-		if err := converter.SaveFrames(frames, *outputFile); err != nil {
+		opts := fileformat.SaveOptions{Compression: fileformat.CompressionGzip, KeyframeInterval: cfg.KeyframeInterval()}
+		if err := converter.SaveFramesWithOptions(frames, *outputFile, opts); err != nil {
 			return err
 		}
 		fmt.Printf("✓ Saved!\n")
@@ -421,11 +486,12 @@ func handleGifImport(cfg *config.Config, flagsSet map[string]bool) error {
 		
 		// If raw mode, play the saved file
 		if *rawMode {
-			aartFile, err := fileformat.Load(*outputFile)
+			src, err := fileformat.Open(*outputFile)
 			if err != nil {
 				return fmt.Errorf("failed to load saved file for raw playback: %v", err)
 			}
-			playRawAnimation(aartFile)
+			defer src.Close()
+			playRawAnimation(src)
 		}
 		
 		return nil
@@ -442,7 +508,7 @@ func handleGifImport(cfg *config.Config, flagsSet map[string]bool) error {
 			return fmt.Errorf("failed to load temp file for raw playback: %v", err)
 		}
 		fmt.Println("🎬 Playing animation...\n")
-		playRawAnimation(aartFile)
+		playRawAnimation(fileformat.NewFrameSource(aartFile))
 		return nil
 	}
 
@@ -483,6 +549,74 @@ func handleGifImport(cfg *config.Config, flagsSet map[string]bool) error {
 	return nil
 }
 
+// handleImport converts --import into an animation by its extension, then
+// saves it, plays it or opens it like a GIF import
+func handleImport(cfg *config.Config) error {
+	source := *importFile
+	var aartFile *fileformat.AartFile
+	var err error
+
+	switch ext := strings.ToLower(filepath.Ext(source)); ext {
+	case ".cast":
+		fmt.Printf("🎨 aart - asciicast import\n\n")
+		fmt.Printf("Source: %s\n", source)
+		fmt.Printf("Sampling: %dfps\n\n", *fps)
+		aartFile, err = fileformat.ImportAsciicast(source, fileformat.CastImportOptions{
+			FPS:    *fps,
+			Width:  *width,
+			Height: *height,
+		})
+	case ".ans":
+		fmt.Printf("🎨 aart - ANSI art import\n\n")
+		fmt.Printf("Source: %s\n\n", source)
+		aartFile, err = fileformat.ImportANS(source)
+	case ".txt":
+		fmt.Printf("🎨 aart - text import\n\n")
+		fmt.Printf("Source: %s\n\n", source)
+		aartFile, err = fileformat.ImportText(source, fileformat.TextImportOptions{
+			Delimiter: *frameDelim,
+			Duration:  1000 / max(*fps, 1),
+		})
+	default:
+		return fmt.Errorf("unsupported import format %q (supported: .gif, .png, .jpg, .jpeg, .bmp, .cast, .ans, .txt)", ext)
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("✓ Imported %d frames (%dx%d)\n\n", aartFile.FrameCount(), aartFile.Canvas.Width, aartFile.Canvas.Height)
+
+	if *outputFile != "" {
+		fmt.Printf("💾 Saving to %s...\n", *outputFile)
+		opts := fileformat.SaveOptions{Compression: fileformat.CompressionGzip, KeyframeInterval: cfg.KeyframeInterval()}
+		if err := fileformat.SaveWithOptions(*outputFile, aartFile, opts); err != nil {
+			return err
+		}
+		fmt.Printf("✓ Saved!\n")
+
+		cfg.AddRecentFile(*outputFile, aartFile.FrameCount())
+		config.Save(cfg)
+	}
+
+	if *rawMode {
+		playRawAnimation(fileformat.NewFrameSource(aartFile))
+		return nil
+	}
+	if *outputFile != "" {
+		return nil
+	}
+
+	fmt.Println("🖼️  Opening in editor...")
+	filename := strings.TrimSuffix(filepath.Base(source), filepath.Ext(source)) + ".aart"
+	p := tea.NewProgram(
+		ui.NewWithFile(cfg, filename, aartFile),
+		tea.WithAltScreen(),
+		tea.WithMouseCellMotion(),
+	)
+	_, err = p.Run()
+	return err
+}
+
 // getTerminalSize returns the current terminal dimensions
 func getTerminalSize() (width, height int) {
 	// Try to get terminal size using TIOCGWINSZ ioctl
@@ -581,6 +715,21 @@ func handleExport(inputFile string) error {
 		FrameIndex:  *exportFrame,
 		IncludeMeta: true,
 		Colors:      *exportColors,
+		Columns:     *sheetColumns,
+		GoPackage:   *goPackage,
+		GoVar:       *goVar,
+		Delimiter:   *frameDelim,
+		Durations:   *frameDurations,
+	}
+	mode, err := fileformat.ParseColorMode(*colorMode)
+	if err != nil {
+		return err
+	}
+	opts.ColorMode = mode
+	if *cellSize != "" {
+		if _, err := fmt.Sscanf(*cellSize, "%dx%d", &opts.CellWidth, &opts.CellHeight); err != nil || opts.CellWidth < 1 || opts.CellHeight < 1 {
+			return fmt.Errorf("invalid --cell-size %q, expected WxH such as 12x20", *cellSize)
+		}
 	}
 
 	// Export
@@ -594,6 +743,12 @@ func handleExport(inputFile string) error {
 	if *exportFrame >= 0 {
 		fmt.Printf("  Frame: %d only\n", *exportFrame)
 	}
+	if opts.Format == fileformat.FormatSpriteSheet {
+		fmt.Printf("  Atlas: %s\n", fileformat.AtlasPath(*exportFile))
+	}
+	if opts.Format == fileformat.FormatANSI && *exportFrame < 0 && aart.FrameCount() > 1 {
+		fmt.Printf("  Player: %s\n", fileformat.PlayerScriptPath(*exportFile))
+	}
 
 	return nil
 }
@@ -604,22 +759,66 @@ func printHelp() {
 USAGE:
     aart [options] [file]
     aart --import-gif <url|path> [options]
+    aart --import <url|path> [options]
     aart --init                    # Initialize configuration
     aart --show-config             # Show current configuration
+    aart validate [--fix] <file>...     # Check (and repair) .aart files
 
 OPTIONS:
     --import-gif <source>    Import GIF from URL or local path
+    --import <source>        Import by extension: a .gif, .png, .jpg or .bmp
+                             image from URL or path, converted with --method,
+                             --ratio and the size options. A directory or a
+                             quoted glob imports an image sequence, one frame
+                             per file in natural order. Or a .cast
+                             asciinema recording, sampled at
+                             --fps; --width/--height crop or pad the screen.
+                             Or a .ans ANSI art file (CP437, SAUCE), or
+                             .txt frames split by --frame-delimiter lines
+                             or form feeds, --fps setting their duration
     --output <file>          Save imported frames to file (default: open editor)
+                             A .aartz extension writes the compact packed format
+    --keyframe-interval <n>  Keyframe every n frames, frames in between are
+                             stored as changed cells only (0 = off); also
+                             applies to saves made from the editor
     --width <int>            Canvas width (default: auto from terminal)
     --height <int>           Canvas height (default: auto from terminal)
     --fps <int>              Target FPS (default: 12)
+    --durations <file>       Image sequence frame durations, "<file> <ms>" or
+                             "<ms>" lines, files relative to the sidecar
+                             (default: 1/fps each)
     --method <string>        Conversion method (default: luminosity)
-                             Options: luminosity, edge, block, dither
+                             Options: luminosity, edge, block, dither, braille
+                             (2x4 dots per cell), halfblock (1x2 pixels per
+                             cell), quadrant (2x2 pixels per cell)
+    --threshold <0-255>      Braille dot threshold (default: the image's mean)
+    --dither <mode>          none, floyd-steinberg, atkinson, bayer4 (or
+                             ordered), bayer8; applies to the characters and
+                             to the colors (default: none, floyd-steinberg
+                             for the dither method)
     --ratio <string>         Aspect ratio (default: fill)
                              Options: fill, fit, original
     --chars <string>         Custom character set for conversion
-    --colors                 Use RGB colors (default: monochrome grayscale)
+    --colors                 Use RGB colors (default: monochrome grayscale),
+                             in the palette of --color-mode 256 or 16
     
+EXPORT:
+    --export <file>          Export the input file to <file>
+    --export-format <fmt>    json, csv, ansi, txt, html, svg, gif, png,
+                             spritesheet, asciicast, bbs, go, frames
+                             (default: json)
+    --export-frame <n>       Export frame n only (default: all)
+    --export-colors          Include colors (default: true)
+    --color-mode <mode>      ANSI/asciicast colors: truecolor, 256, 16, none
+                             (default: truecolor); also quantizes the colors
+                             of an import with --colors
+    --cell-size <WxH>        Pixels per cell for image formats (default: 12x20)
+    --sheet-columns <n>      Frames per row of a sprite sheet (default: square)
+    --go-package <name>      Package of a Go export (default: main)
+    --go-var <name>          Variable of a Go export (default: from the file name)
+    --frame-delimiter <line> Line between text frames (default: %%)
+    --frame-durations        Start each text frame with "%% 100ms" headers
+
 CONFIGURATION:
     --init                   Initialize ~/.config/aart directory
     --show-config            Display current configuration
@@ -633,7 +832,7 @@ CONVERSION METHODS:
     luminosity    Convert based on brightness (default)
     edge          Edge detection with line characters
     block         Block characters (░▒▓█)
-    dither        Dithered output
+    dither        Shading characters with Floyd-Steinberg dithering
 
 CONFIGURATION FILE:
     Location: ~/.config/aart/config.yml
@@ -662,22 +861,94 @@ EXAMPLES:
     # Import and save to file
     aart --import-gif animation.gif --output animation.aart
 
+    # Import and save as a compact packed file
+    aart --import-gif animation.gif --output animation.aartz
+
+    # Render an animated GIF with 8x16 pixel cells
+    aart --export animation.gif --export-format gif --cell-size 8x16 animation.aart
+
+    # Sprite sheet of all frames plus animation.json with the frame rects
+    aart --export animation.png --export-format spritesheet animation.aart
+
+    # Animated ANSI for 256-color terminals, plus animation.sh to play it
+    aart --export animation.ans --export-format ansi --color-mode 256 animation.aart
+
+    # asciinema recording of the animation, and back
+    aart --export animation.cast --export-format asciicast animation.aart
+    aart --import demo.cast --fps 24 --output demo.aart
+
+    # Export a frame as BBS ANSI art with a SAUCE record, and import one
+    aart --export logo.ans --export-format bbs logo.aart
+    aart --import logo.ans --output logo.aart
+
+    # All frames as text to edit in any editor, and back
+    aart --export walk.txt --export-format frames --frame-durations walk.aart
+    aart --import walk.txt --output walk.aart
+
+    # Go source with the frames and a player, to embed in a CLI
+    aart --export spinner.go --export-format go --go-package ui --go-var Spinner spinner.aart
+
     # Import with specific method
     aart --import-gif animation.gif --method block
 
+    # Near-photographic truecolor with two pixels per cell
+    aart --import photo.jpg --method halfblock --colors
+
+    # Braille dots, 2x4 pixels per cell, with ordered dithering
+    aart --import photo.png --method braille --dither ordered --colors
+
+    # Half blocks in the 16 ANSI colors, dithered with Atkinson
+    aart --import photo.jpg --method halfblock --colors --color-mode 16 --dither atkinson
+
+    # Rendered frames frame_0001.png ... as an animation
+    aart --import 'render/frame_*.png' --fps 24 --output render.aart
+    aart --import render/ --durations render/durations.txt --output render.aart
+
+    # Convert a screenshot or logo into a single frame
+    aart --import logo.png --width 60 --ratio fit --colors --output logo.aart
+
     # Show current configuration
     aart --show-config
 
+    # Check a file against the format schema
+    aart validate animation.aart
+
+    # Repair colors, row sizes, indices and durations, keeping a report
+    aart validate --fix --report report.txt animation.aart
+
     # Open existing file
     aart animation.aart
 
 KEYBOARD SHORTCUTS (in editor):
     hjkl / arrows    Navigate cursor
     i                Insert mode
-    d                Draw character
+    d                Apply current tool at cursor
+    p f s L b t e m  Tools: pencil, fill, select, line, box, text,
+                     eyedropper, move (line/box/move take two points)
+    :fill [4|8] [char|fg|bg]    Flood fill connectivity and match
+    :border <style>  Box style: ascii, single, double, rounded, heavy
+    :text <string>   Set the text tool stamp
+    u / ctrl-r       Undo / redo (:undo N, :redo N)
+    v / V / ctrl-v   Visual, line and block selection
+                     (y yank, d delete, m move, "x register prefix,
+                     "+y also copies to the system clipboard)
+    P / ctrl-p       Paste register (ctrl-p skips spaces)
+    :paste [reg] [from-to] [t]  Paste a register into a frame range
+    :layer [N]       List layers or select layer N
+    :layer add|del|merge|flatten [name]
+    :layer move up|down|N
+    :layer show|hide|toggle|rename|opacity|blend
+                     Blend modes: normal, multiply, char-only, color-only
     space            Play/pause
     , .              Seek frames
+    n / N            New blank frame after / before the current one
+    c / X            Duplicate / delete the current frame
+    < / >            Move the current frame left / right
+    [ / ]            Shorten / lengthen the current frame by 10ms
+    :frame [N|new|dup|del|move left|right|N|delay MS]
+    :frames N-M delete|dup|delay MS   Apply to a frame range
     z                Toggle zen mode
+    o                Toggle onion skin (:onion PREV NEXT sets the frame counts)
     ctrl-j/k         Cycle wheel menu
     :                Command mode
     q                Quit
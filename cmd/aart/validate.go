@@ -0,0 +1,246 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mlamkadm/aart/internal/config"
+	"github.com/mlamkadm/aart/internal/fileformat"
+)
+
+// maxListedIssues caps how many issues are printed per file; the --report
+// file always has all of them
+const maxListedIssues = 50
+
+// validateOptions are the flags of "aart validate"
+type validateOptions struct {
+	fix              bool
+	output           string
+	keyframeInterval int // delta-encode repaired files, as saves do
+}
+
+// runValidate implements "aart validate [--fix] [--report FILE] <file>..."
+// and returns the process exit code
+func runValidate(args []string) int {
+	fs := flag.NewFlagSet("validate", flag.ContinueOnError)
+	printSchema := fs.Bool("schema", false, "Print the JSON Schema files are checked against")
+	fix := fs.Bool("fix", false, "Repair what can be repaired and save the file, keeping the original as FILE.bak")
+	output := fs.String("output", "", "With --fix, write the repaired file here instead of in place")
+	reportPath := fs.String("report", "", "Write the full list of issues to this file")
+	interval := fs.Int("keyframe-interval", -1, "With --fix, store frames between keyframes as deltas, a keyframe every N frames (0 = all keyframes, -1 = config)")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: aart validate [--fix [--output FILE]] [--report FILE] <file.aart>...\n")
+		fmt.Fprintf(fs.Output(), "       aart validate --schema\n\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	if *printSchema {
+		os.Stdout.Write(fileformat.Schema)
+		return 0
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+	if *output != "" && (!*fix || fs.NArg() > 1) {
+		fmt.Fprintln(os.Stderr, "Error: --output needs --fix and a single input file")
+		return 2
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		cfg = &config.DefaultConfig
+	}
+	if *interval >= 0 {
+		cfg.KeyframeIntervalOverride = interval
+	}
+
+	opts := validateOptions{fix: *fix, output: *output, keyframeInterval: cfg.KeyframeInterval()}
+	var report strings.Builder
+	status := 0
+	for _, path := range fs.Args() {
+		if !validateFile(path, opts, &report) {
+			status = 1
+		}
+	}
+
+	if *reportPath != "" {
+		if err := os.WriteFile(*reportPath, []byte(report.String()), 0644); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing report: %v\n", err)
+			return 1
+		}
+		fmt.Printf("Report written to %s\n", *reportPath)
+	}
+	return status
+}
+
+// validateFile checks one file against the schema and the format rules,
+// printing what it finds and appending every issue to report. With --fix
+// the file is repaired and saved to --output, or in place after copying
+// the original to <file>.bak. It reports whether the file is valid.
+func validateFile(path string, opts validateOptions, report *strings.Builder) bool {
+	fmt.Fprintf(report, "%s\n", path)
+
+	fail := func(err error) bool {
+		fmt.Printf("✗ %s: %v\n", path, err)
+		fmt.Fprintf(report, "  error: %v\n", err)
+		return false
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fail(err)
+	}
+	doc, from, err := validationDocument(data)
+	if err != nil {
+		return fail(err)
+	}
+	schemaErrs, err := fileformat.ValidateDocument(doc)
+	if err != nil {
+		return fail(err)
+	}
+
+	var lines []string
+	for _, e := range schemaErrs {
+		lines = append(lines, "schema: "+e.Error())
+	}
+
+	// Deep checks on the decoded file: everything the schema cannot
+	// express, with locations and severities
+	aart, err := fileformat.Load(path)
+	if err != nil {
+		for _, l := range lines {
+			fmt.Fprintf(report, "  %s\n", l)
+		}
+		return fail(err)
+	}
+	var issues []fileformat.Issue
+	if opts.fix {
+		issues = aart.Repair()
+	} else {
+		issues = aart.Check()
+	}
+
+	fixed := 0
+	for _, issue := range issues {
+		lines = append(lines, issue.String())
+		if issue.Fixed != "" {
+			fixed++
+		}
+	}
+	for _, l := range lines {
+		fmt.Fprintf(report, "  %s\n", l)
+	}
+
+	// A repaired file is re-encoded from the decoded data, which also
+	// settles schema errors
+	valid := !fileformat.HasErrors(issues) && (len(schemaErrs) == 0 || opts.fix)
+	mark := "✓"
+	if !valid {
+		mark = "✗"
+	}
+	fmt.Printf("%s %s: %s (version %s)\n", mark, path, summarizeIssues(issues, len(schemaErrs)), from)
+	for i, l := range lines {
+		if i == maxListedIssues {
+			fmt.Printf("  ... %d more, use --report to list them all\n", len(lines)-i)
+			break
+		}
+		fmt.Printf("  %s\n", l)
+	}
+
+	if !opts.fix {
+		if from != fileformat.CurrentVersion {
+			fmt.Printf("  will be upgraded to %s on save\n", fileformat.CurrentVersion)
+		}
+		return valid
+	}
+
+	if fixed == 0 && len(schemaErrs) == 0 && from == fileformat.CurrentVersion && opts.output == "" {
+		return valid
+	}
+	target := path
+	if opts.output != "" {
+		target = opts.output
+	} else {
+		// Keep the original next to the file, a wrong repair must not cost
+		// the user their work
+		backup := path + ".bak"
+		if err := os.WriteFile(backup, data, 0644); err != nil {
+			return fail(fmt.Errorf("writing backup: %w", err))
+		}
+		fmt.Printf("  original kept as %s\n", backup)
+		fmt.Fprintf(report, "  original kept as %s\n", backup)
+	}
+	saveOpts := fileformat.SaveOptions{Compression: fileformat.CompressionGzip, KeyframeInterval: opts.keyframeInterval}
+	if err := fileformat.SaveWithOptions(target, aart, saveOpts); err != nil {
+		return fail(fmt.Errorf("saving repaired file: %w", err))
+	}
+	fmt.Printf("  repaired %d issue(s), saved to %s\n", fixed, target)
+	fmt.Fprintf(report, "  repaired %d issue(s), saved to %s\n", fixed, target)
+	return valid
+}
+
+// summarizeIssues counts issues by severity, e.g. "2 errors, 1 warning"
+func summarizeIssues(issues []fileformat.Issue, schemaErrs int) string {
+	counts := map[fileformat.Severity]int{fileformat.SeverityError: schemaErrs}
+	for _, issue := range issues {
+		counts[issue.Severity]++
+	}
+	var parts []string
+	for _, sev := range []fileformat.Severity{fileformat.SeverityError, fileformat.SeverityWarning, fileformat.SeverityInfo} {
+		if n := counts[sev]; n > 0 {
+			name := sev.String()
+			if n > 1 && sev != fileformat.SeverityInfo {
+				name += "s"
+			}
+			parts = append(parts, fmt.Sprintf("%d %s", n, name))
+		}
+	}
+	if len(parts) == 0 {
+		return "valid"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// validationDocument decodes a file into a generic JSON document at the
+// current schema version and returns the version it was written with.
+// Packed files are checked through their decoded JSON form.
+func validationDocument(data []byte) (any, string, error) {
+	if fileformat.IsPacked(data) {
+		p, err := fileformat.NewPackedReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return nil, "", err
+		}
+		from := p.Header().Version
+		aart, err := p.ReadAll()
+		if err != nil {
+			return nil, from, err
+		}
+		if data, err = json.Marshal(aart); err != nil {
+			return nil, from, err
+		}
+		var doc any
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return nil, from, err
+		}
+		return doc, from, nil
+	}
+
+	var doc any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, "", fmt.Errorf("not valid JSON: %w", err)
+	}
+	obj, ok := doc.(map[string]any)
+	if !ok {
+		return doc, "", nil
+	}
+	from, err := fileformat.Migrate(obj)
+	return obj, from, err
+}
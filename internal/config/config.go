@@ -21,6 +21,10 @@ type Config struct {
 	Converter ConvertConfig  `yaml:"converter"`
 	Startup   StartupConfig  `yaml:"startup"`
 	Keybinds  KeyBindings    `yaml:"keybindings,omitempty"`
+
+	// KeyframeIntervalOverride is set from --keyframe-interval for the
+	// current run and never written back to config.yml
+	KeyframeIntervalOverride *int `yaml:"-"`
 }
 
 // EditorConfig contains editor preferences
@@ -34,6 +38,11 @@ type EditorConfig struct {
 	ShowGrid      bool   `yaml:"show_grid"`
 	ShowLineNumbers bool `yaml:"show_line_numbers"`
 	ZenMode       bool   `yaml:"zen_mode"`
+	UndoMemoryMB  int    `yaml:"undo_memory_mb"` // undo history budget, 0 = default, -1 = unlimited
+	OnionSkin     bool   `yaml:"onion_skin"`      // show ghosts of adjacent frames
+	OnionPrev     int    `yaml:"onion_prev"`      // previous frames shown
+	OnionNext     int    `yaml:"onion_next"`      // next frames shown
+	KeyframeInterval int `yaml:"keyframe_interval"` // delta-encode saved frames, 0 = off
 }
 
 // UIConfig contains UI preferences
@@ -78,7 +87,7 @@ type RecentFile struct {
 
 // ConvertConfig contains GIF conversion preferences
 type ConvertConfig struct {
-	DefaultMethod string `yaml:"default_method"` // luminosity, block, edge, dither
+	DefaultMethod string `yaml:"default_method"` // luminosity, block, edge, dither, braille, halfblock, quadrant
 	DefaultChars  string `yaml:"default_chars,omitempty"`
 	PreserveAspect bool  `yaml:"preserve_aspect"`
 	Quality       string `yaml:"quality"` // low, medium, high
@@ -125,6 +134,11 @@ var (
 			ShowGrid:         false,
 			ShowLineNumbers:  false,
 			ZenMode:          false,
+			UndoMemoryMB:     64,
+			OnionSkin:        false,
+			OnionPrev:        1,
+			OnionNext:        1,
+			KeyframeInterval: 0,
 		},
 		UI: UIConfig{
 			Theme:              "tokyo-night",
@@ -300,6 +314,13 @@ func mergeDefaults(config *Config) {
 	if config.Editor.AutoSaveInterval == 0 {
 		config.Editor.AutoSaveInterval = DefaultConfig.Editor.AutoSaveInterval
 	}
+	if config.Editor.UndoMemoryMB == 0 {
+		config.Editor.UndoMemoryMB = DefaultConfig.Editor.UndoMemoryMB
+	}
+	if config.Editor.OnionPrev == 0 && config.Editor.OnionNext == 0 {
+		config.Editor.OnionPrev = DefaultConfig.Editor.OnionPrev
+		config.Editor.OnionNext = DefaultConfig.Editor.OnionNext
+	}
 	if config.Recent.Max == 0 {
 		config.Recent.Max = DefaultConfig.Recent.Max
 	}
@@ -339,6 +360,15 @@ func (c *Config) AddRecentFile(path string, frames int) {
 	}
 }
 
+// KeyframeInterval returns the keyframe interval saves should use, the
+// command line override if any, the editor setting otherwise
+func (c *Config) KeyframeInterval() int {
+	if c.KeyframeIntervalOverride != nil {
+		return *c.KeyframeIntervalOverride
+	}
+	return c.Editor.KeyframeInterval
+}
+
 // GetRecentFiles returns the list of recent files
 func (c *Config) GetRecentFiles() []RecentFile {
 	return c.Recent.Files
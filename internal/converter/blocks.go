@@ -0,0 +1,176 @@
+package converter
+
+import (
+	"fmt"
+	"image"
+
+	"github.com/mlamkadm/aart/internal/fileformat"
+)
+
+// quadrantChars holds the character for each set of filled quadrants,
+// bits 1 upper left, 2 upper right, 4 lower left, 8 lower right
+var quadrantChars = [16]rune{
+	' ', '▘', '▝', '▀', '▖', '▌', '▞', '▛',
+	'▗', '▚', '▐', '▜', '▄', '▙', '▟', '█',
+}
+
+// pixelGrid holds the colors of an image, transparent pixels as black
+type pixelGrid struct {
+	width, height int
+	pix           [][3]float64
+	opaque        []bool
+}
+
+func newPixelGrid(img image.Image) *pixelGrid {
+	bounds := img.Bounds()
+	g := &pixelGrid{width: bounds.Dx(), height: bounds.Dy()}
+	g.pix = make([][3]float64, g.width*g.height)
+	g.opaque = make([]bool, g.width*g.height)
+	for y := 0; y < g.height; y++ {
+		for x := 0; x < g.width; x++ {
+			r, gr, b, a := img.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+			if a >= 0x8000 {
+				i := y*g.width + x
+				g.pix[i] = [3]float64{float64(r >> 8), float64(gr >> 8), float64(b >> 8)}
+				g.opaque[i] = true
+			}
+		}
+	}
+	return g
+}
+
+// luminosity returns a grid of the luminosities of g, rounded down, in
+// the first channel
+func (g *pixelGrid) luminosity() *pixelGrid {
+	lum := &pixelGrid{width: g.width, height: g.height, pix: make([][3]float64, len(g.pix)), opaque: g.opaque}
+	for i, c := range g.pix {
+		if g.opaque[i] {
+			lum.pix[i][0] = float64(int(luminosity(c)))
+		}
+	}
+	return lum
+}
+
+// at returns the pixel at x, y, black outside the image
+func (g *pixelGrid) at(x, y int) [3]float64 {
+	if x >= g.width || y >= g.height {
+		return [3]float64{}
+	}
+	return g.pix[y*g.width+x]
+}
+
+// convertHalfblock converts an image with 1x2 pixels per cell into upper
+// half blocks: the top pixel is the foreground, the bottom one the
+// background. Each pixel is a color, so dithering works on the pixels.
+func convertHalfblock(img image.Image, opts Options) *Frame {
+	g := newPixelGrid(img)
+	ditherColors(g, opts)
+	width, height := g.width, (g.height+1)/2
+
+	cells := make([][]Cell, height)
+	for cy := range cells {
+		cells[cy] = make([]Cell, width)
+		for cx := range cells[cy] {
+			cells[cy][cx] = Cell{
+				Char: '▀',
+				FG:   cellColor(g.at(cx, cy*2), opts),
+				BG:   cellColor(g.at(cx, cy*2+1), opts),
+			}
+		}
+	}
+	return &Frame{Width: width, Height: height, Cells: cells}
+}
+
+// convertQuadrant converts an image with 2x2 pixels per cell into
+// quadrant blocks. Each cell splits its four pixels into the two groups
+// whose average colors fit them best: the quadrants of one group are
+// drawn in its color as foreground, the rest is background. Dithering
+// works on the pixels, before they are split.
+func convertQuadrant(img image.Image, opts Options) *Frame {
+	g := newPixelGrid(img)
+	ditherColors(g, opts)
+	width, height := (g.width+1)/2, (g.height+1)/2
+
+	cells := make([][]Cell, height)
+	for cy := range cells {
+		cells[cy] = make([]Cell, width)
+		for cx := range cells[cy] {
+			block := [4][3]float64{
+				g.at(cx*2, cy*2), g.at(cx*2+1, cy*2),
+				g.at(cx*2, cy*2+1), g.at(cx*2+1, cy*2+1),
+			}
+			mask, fg, bg := fitQuadrants(block)
+			cells[cy][cx] = Cell{
+				Char: quadrantChars[mask],
+				FG:   cellColor(fg, opts),
+				BG:   cellColor(bg, opts),
+			}
+		}
+	}
+	return &Frame{Width: width, Height: height, Cells: cells}
+}
+
+// fitQuadrants finds the split of four pixels into foreground (the bits
+// of mask) and background with the least squared error from the two
+// average colors. A uniform block is a full block.
+func fitQuadrants(block [4][3]float64) (mask int, fg, bg [3]float64) {
+	best := -1.0
+	// Splits and their complements are the same, so the upper left
+	// pixel is always foreground. Going down from the full block makes
+	// it win ties.
+	for m := 15; m > 0; m -= 2 {
+		var sums [2][3]float64
+		var counts [2]int
+		for i, p := range block {
+			side := 1
+			if m&(1<<i) != 0 {
+				side = 0
+			}
+			counts[side]++
+			for c := range p {
+				sums[side][c] += p[c]
+			}
+		}
+		var means [2][3]float64
+		for side := range means {
+			if counts[side] == 0 {
+				continue
+			}
+			for c := range means[side] {
+				means[side][c] = sums[side][c] / float64(counts[side])
+			}
+		}
+		if counts[1] == 0 {
+			means[1] = means[0]
+		}
+
+		err := 0.0
+		for i, p := range block {
+			mean := means[1]
+			if m&(1<<i) != 0 {
+				mean = means[0]
+			}
+			for c := range p {
+				d := p[c] - mean[c]
+				err += d * d
+			}
+		}
+		if best < 0 || err < best {
+			best, mask, fg, bg = err, m, means[0], means[1]
+		}
+	}
+	return mask, fg, bg
+}
+
+// cellColor formats a color for a cell: as is with opts.UseColors, or
+// the nearest color of opts.Palette, otherwise reduced to the 16 gray
+// levels of monochrome mode
+func cellColor(c [3]float64, opts Options) string {
+	r, g, b := uint8(clampChannel(c[0])), uint8(clampChannel(c[1])), uint8(clampChannel(c[2]))
+	if opts.UseColors {
+		r, g, b = fileformat.QuantizeColor(opts.Palette, r, g, b)
+		return fmt.Sprintf("#%02X%02X%02X", r, g, b)
+	}
+	gray := int(luminosity([3]float64{float64(r), float64(g), float64(b)})) / 16 * 17
+	return fmt.Sprintf("#%02X%02X%02X", gray, gray, gray)
+}
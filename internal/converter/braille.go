@@ -0,0 +1,96 @@
+package converter
+
+import "image"
+
+// brailleDots holds the bit of each dot of a braille cell, by row and
+// column of its 2x4 block
+var brailleDots = [4][2]rune{
+	{0x01, 0x08},
+	{0x02, 0x10},
+	{0x04, 0x20},
+	{0x40, 0x80},
+}
+
+// convertBraille converts an image with 2x4 pixels per cell into braille
+// patterns (U+2800-U+28FF). A pixel becomes a dot when its luminosity
+// passes opts.Threshold, or the image's mean luminosity without one;
+// opts.Dither spreads the error or the threshold so gradients keep their
+// shading. The cell's color is the average color of its block, dithered
+// across cells.
+func convertBraille(img image.Image, opts Options) *Frame {
+	g := newPixelGrid(img)
+	width, height := (g.width+1)/2, (g.height+3)/4
+
+	lum := g.luminosity()
+	sum, opaque := 0.0, 0
+	for i, v := range lum.pix {
+		if lum.opaque[i] {
+			sum += v[0]
+			opaque++
+		}
+	}
+	threshold := float64(opts.Threshold)
+	if threshold <= 0 && opaque > 0 {
+		threshold = float64(int(sum) / opaque)
+	}
+	lum.dither(opts.ditherMode(), 255, func(v [3]float64) [3]float64 {
+		if v[0] > threshold {
+			return [3]float64{255}
+		}
+		return [3]float64{}
+	})
+
+	patterns := make([]rune, width*height)
+	colors := &pixelGrid{width: width, height: height, pix: make([][3]float64, width*height), opaque: make([]bool, width*height)}
+	for cy := 0; cy < height; cy++ {
+		for cx := 0; cx < width; cx++ {
+			pattern := rune(0)
+			var sum [3]float64
+			n := 0
+			for dy := 0; dy < 4; dy++ {
+				for dx := 0; dx < 2; dx++ {
+					x, y := cx*2+dx, cy*4+dy
+					if x >= g.width || y >= g.height || !g.opaque[y*g.width+x] {
+						continue
+					}
+					for c := range sum {
+						sum[c] += g.pix[y*g.width+x][c]
+					}
+					n++
+					if lum.pix[y*g.width+x][0] > 0 {
+						pattern |= brailleDots[dy][dx]
+					}
+				}
+			}
+			i := cy*width + cx
+			patterns[i] = pattern
+			if n > 0 && pattern != 0 {
+				for c := range sum {
+					// Rounded down like the other methods
+					colors.pix[i][c] = float64(int(sum[c]) / n)
+				}
+				colors.opaque[i] = true
+			}
+		}
+	}
+	ditherColors(colors, opts)
+
+	cells := make([][]Cell, height)
+	for cy := 0; cy < height; cy++ {
+		cells[cy] = make([]Cell, width)
+		for cx := 0; cx < width; cx++ {
+			i := cy*width + cx
+			if !colors.opaque[i] {
+				cells[cy][cx] = Cell{Char: ' ', FG: "#FFFFFF", BG: "#000000"}
+				continue
+			}
+			cells[cy][cx] = Cell{Char: 0x2800 + patterns[i], FG: cellColor(colors.pix[i], opts), BG: "#000000"}
+		}
+	}
+
+	return &Frame{
+		Width:  width,
+		Height: height,
+		Cells:  cells,
+	}
+}
@@ -1,16 +1,16 @@
 package converter
 
 import (
-	"encoding/json"
 	"fmt"
 	"image"
 	"image/gif"
 	"io"
+	"math"
 	"net/http"
 	"os"
 	"strings"
-	"time"
 
+	"github.com/mlamkadm/aart/internal/fileformat"
 	"github.com/nfnt/resize"
 )
 
@@ -21,7 +21,11 @@ type Options struct {
 	Method           string
 	Ratio            string // "fill", "fit", "original"
 	Chars            string
-	UseColors        bool   // If true, include RGB colors; if false, monochrome
+	UseColors        bool                 // If true, include RGB colors; if false, monochrome
+	Palette          fileformat.ColorMode // With UseColors: "256" or "16" quantizes to that palette
+	Threshold        int                  // Braille: luminosity a dot needs (0 = the image's mean)
+	Dither           string               // none, floyd-steinberg, atkinson, bayer4 (ordered), bayer8
+	Durations        string               // Image sequences: sidecar file of per-file durations
 	ProgressCallback func(current, total int, message string)
 }
 
@@ -62,7 +66,11 @@ func ConvertGifToFrames(source string, opts Options) ([]*Frame, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to load GIF: %w", err)
 	}
+	return convertGif(gifData, opts)
+}
 
+// convertGif converts the frames of a decoded GIF
+func convertGif(gifData *gif.GIF, opts Options) ([]*Frame, error) {
 	if opts.ProgressCallback != nil {
 		opts.ProgressCallback(10, 100, fmt.Sprintf("Processing %d frames...", len(gifData.Image)))
 	}
@@ -95,11 +103,8 @@ func ConvertGifToFrames(source string, opts Options) ([]*Frame, error) {
 		// Calculate resize dimensions based on ratio mode
 		targetWidth, targetHeight := calculateDimensions(composited, opts)
 		
-		// Resize image to target dimensions
-		resized := resize.Resize(uint(targetWidth), uint(targetHeight), composited, resize.Lanczos3)
-		
-		// Convert to ASCII
-		frame := convertImageToASCII(resized, opts)
+		// Resize and convert to ASCII
+		frame := convertImage(composited, targetWidth, targetHeight, opts)
 		
 		// Calculate delay in milliseconds
 		delay := gifData.Delay[i] * 10 // GIF delay is in 100ths of a second
@@ -196,24 +201,37 @@ func compositeImages(dst, src image.Image) image.Image {
 	return composited
 }
 
-// loadGif loads a GIF from URL or local file
-func loadGif(source string) (*gif.GIF, error) {
-	var reader io.ReadCloser
-	var err error
-
-	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+// openSource opens a URL or a local file
+func openSource(source string) (io.ReadCloser, error) {
+	if isURL(source) {
 		// Load from URL
 		resp, err := http.Get(source)
 		if err != nil {
 			return nil, fmt.Errorf("failed to fetch URL: %w", err)
 		}
-		reader = resp.Body
-	} else {
-		// Load from file
-		reader, err = os.Open(source)
-		if err != nil {
-			return nil, fmt.Errorf("failed to open file: %w", err)
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return nil, fmt.Errorf("failed to fetch URL: %s", resp.Status)
 		}
+		return resp.Body, nil
+	}
+	// Load from file
+	file, err := os.Open(source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	return file, nil
+}
+
+func isURL(source string) bool {
+	return strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://")
+}
+
+// loadGif loads a GIF from URL or local file
+func loadGif(source string) (*gif.GIF, error) {
+	reader, err := openSource(source)
+	if err != nil {
+		return nil, err
 	}
 	defer reader.Close()
 
@@ -225,235 +243,150 @@ func loadGif(source string) (*gif.GIF, error) {
 	return gifData, nil
 }
 
-// convertImageToASCII converts a single image to ASCII
-func convertImageToASCII(img image.Image, opts Options) *Frame {
-	bounds := img.Bounds()
-	width := bounds.Dx()
-	height := bounds.Dy()
-
-	cells := make([][]Cell, height)
-	for y := 0; y < height; y++ {
-		cells[y] = make([]Cell, width)
-		for x := 0; x < width; x++ {
-			r, g, b, a := img.At(x, y).RGBA()
-			
-			// Convert to 8-bit
-			r8 := uint8(r >> 8)
-			g8 := uint8(g >> 8)
-			b8 := uint8(b >> 8)
-			a8 := uint8(a >> 8)
+// convertImage resizes an image to width x height cells and converts it
+// with opts.Method. Braille cells hold 2x4 pixels, half blocks 1x2,
+// quadrants 2x2 and the others one.
+func convertImage(img image.Image, width, height int, opts Options) *Frame {
+	switch opts.Method {
+	case "braille":
+		resized := resize.Resize(uint(width*2), uint(height*4), img, resize.Lanczos3)
+		return convertBraille(resized, opts)
+	case "halfblock":
+		resized := resize.Resize(uint(width), uint(height*2), img, resize.Lanczos3)
+		return convertHalfblock(resized, opts)
+	case "quadrant":
+		resized := resize.Resize(uint(width*2), uint(height*2), img, resize.Lanczos3)
+		return convertQuadrant(resized, opts)
+	}
+	resized := resize.Resize(uint(width), uint(height), img, resize.Lanczos3)
+	return convertImageToASCII(resized, opts)
+}
 
-			char, fg, bg := convertPixel(r8, g8, b8, a8, opts)
+// convertImageToASCII converts a single image to ASCII. With opts.Dither
+// each pixel takes the nearest level of the method's character ramp and
+// the nearest color, the error going to its neighbors; the dither method
+// uses Floyd-Steinberg unless opts.Dither names another.
+func convertImageToASCII(img image.Image, opts Options) *Frame {
+	g := newPixelGrid(img)
+	ramp := charRamp(opts)
+	mode := opts.ditherMode()
+	if opts.Method == "dither" && opts.Dither == "" {
+		mode = DitherFloydSteinberg
+	}
+	opts.Dither = mode
+
+	lum := g.luminosity()
+	levels := make([]int, len(lum.pix))
+	if mode == DitherNone || len(ramp) < 2 {
+		for i, v := range lum.pix {
+			levels[i] = int(v[0]) * len(ramp) / 256
+			if levels[i] >= len(ramp) {
+				levels[i] = len(ramp) - 1
+			}
+		}
+	} else {
+		step := 255 / float64(len(ramp)-1)
+		lum.dither(mode, step, func(v [3]float64) [3]float64 {
+			return [3]float64{math.Round(clampChannel(v[0])/step) * step}
+		})
+		for i, v := range lum.pix {
+			levels[i] = int(math.Round(v[0] / step))
+		}
+	}
+	ditherColors(g, opts)
+
+	cells := make([][]Cell, g.height)
+	for y := 0; y < g.height; y++ {
+		cells[y] = make([]Cell, g.width)
+		for x := 0; x < g.width; x++ {
+			i := y*g.width + x
+			// Handle transparency
+			if !g.opaque[i] {
+				cells[y][x] = Cell{Char: ' ', FG: "#FFFFFF", BG: "#000000"}
+				continue
+			}
 			cells[y][x] = Cell{
-				Char: char,
-				FG:   fg,
-				BG:   bg,
+				Char: ramp[levels[i]],
+				FG:   cellColor(g.pix[i], opts),
+				BG:   "#000000",
 			}
 		}
 	}
 
 	return &Frame{
-		Width:  width,
-		Height: height,
+		Width:  g.width,
+		Height: g.height,
 		Cells:  cells,
 	}
 }
 
-// convertPixel converts a pixel to ASCII character and colors
-func convertPixel(r, g, b, a uint8, opts Options) (rune, string, string) {
-	// Handle transparency
-	if a < 128 {
-		return ' ', "#FFFFFF", "#000000"
+// Character ramps of the methods, from dark to bright
+var (
+	// Extended ramp for better gradation
+	luminosityRamp = []rune{
+		' ', '·', '`', '.', ',', ':', ';', '-', '~', '=', '+',
+		'*', 'o', 'x', 'O', 'X', '#', '%', '&', '@', '█',
 	}
+	blockRamp  = []rune{' ', '░', '▒', '▓', '█'}
+	edgeRamp   = []rune{' ', '·', '─', '━'} // line drawing (simplified)
+	ditherRamp = []rune{' ', '·', ':', '░', '▒', '▓', '█'}
+)
 
-	// Calculate luminosity
-	luminosity := uint8((0.299*float64(r) + 0.587*float64(g) + 0.114*float64(b)))
-
-	var char rune
-	
-	// Select character based on method
+// charRamp returns the characters of opts.Method, or the custom set
+func charRamp(opts Options) []rune {
 	switch opts.Method {
 	case "edge":
-		char = getEdgeChar(luminosity)
+		return edgeRamp
 	case "block":
-		char = getBlockChar(luminosity)
+		return blockRamp
 	case "dither":
-		char = getDitherChar(luminosity, r, g, b)
-	case "luminosity":
-		fallthrough
-	default:
-		if opts.Chars != "" {
-			char = getCustomChar(luminosity, opts.Chars)
-		} else {
-			char = getLuminosityChar(luminosity)
-		}
+		return ditherRamp
 	}
-
-	// Return colors based on mode
-	var fg, bg string
-	if opts.UseColors {
-		// Full RGB color mode
-		fg = fmt.Sprintf("#%02X%02X%02X", r, g, b)
-		bg = "#000000"
-	} else {
-		// Monochrome mode - quantize to 16 gray levels for consistency
-		// This reduces color variations and makes frames more similar
-		grayLevel := int(luminosity) / 16 // 0-15
-		quantized := uint8(grayLevel * 17) // Map back to 0-255 in steps of 17
-		fg = fmt.Sprintf("#%02X%02X%02X", quantized, quantized, quantized)
-		bg = "#000000"
-	}
-
-	return char, fg, bg
-}
-
-// getLuminosityChar returns character based on brightness
-func getLuminosityChar(lum uint8) rune {
-	// Extended ramp for better gradation
-	extendedChars := []rune{
-		' ', '·', '`', '.', ',', ':', ';', '-', '~', '=', '+', 
-		'*', 'o', 'x', 'O', 'X', '#', '%', '&', '@', '█',
-	}
-	
-	idx := int(lum) * len(extendedChars) / 256
-	if idx >= len(extendedChars) {
-		idx = len(extendedChars) - 1
-	}
-	
-	return extendedChars[idx]
-}
-
-// getBlockChar returns block drawing character
-func getBlockChar(lum uint8) rune {
-	blocks := []rune{' ', '░', '▒', '▓', '█'}
-	idx := int(lum) * len(blocks) / 256
-	if idx >= len(blocks) {
-		idx = len(blocks) - 1
-	}
-	return blocks[idx]
-}
-
-// getEdgeChar returns line drawing character (simplified)
-func getEdgeChar(lum uint8) rune {
-	if lum < 64 {
-		return ' '
-	} else if lum < 128 {
-		return '·'
-	} else if lum < 192 {
-		return '─'
-	} else {
-		return '━'
-	}
-}
-
-// getDitherChar returns dithered character
-func getDitherChar(lum uint8, r, g, b uint8) rune {
-	// Simple dithering using brightness
-	patterns := []rune{' ', '·', ':', '░', '▒', '▓', '█'}
-	idx := int(lum) * len(patterns) / 256
-	if idx >= len(patterns) {
-		idx = len(patterns) - 1
-	}
-	return patterns[idx]
-}
-
-// getCustomChar uses custom character set
-func getCustomChar(lum uint8, chars string) rune {
-	runes := []rune(chars)
-	if len(runes) == 0 {
-		return ' '
-	}
-	idx := int(lum) * len(runes) / 256
-	if idx >= len(runes) {
-		idx = len(runes) - 1
+	if opts.Chars != "" {
+		return []rune(opts.Chars)
 	}
-	return runes[idx]
+	return luminosityRamp
 }
 
-// SaveFrames saves frames to .aart format
-func SaveFrames(frames []*Frame, filename string) error {
-	if len(frames) == 0 {
-		return fmt.Errorf("no frames to save")
-	}
-
-	// Create .aart file structure
-	aartFile := &struct {
-		Version  string `json:"version"`
-		Metadata struct {
-			Title    string `json:"title"`
-			Created  string `json:"created"`
-			Modified string `json:"modified"`
-			Source   string `json:"source"`
-		} `json:"metadata"`
-		Canvas struct {
-			Width  int `json:"width"`
-			Height int `json:"height"`
-		} `json:"canvas"`
-		Frames []struct {
-			Index    int `json:"index"`
-			Duration int `json:"duration"`
-			Cells    [][]struct {
-				Char       string `json:"char"`
-				Foreground string `json:"fg"`
-				Background string `json:"bg"`
-			} `json:"cells"`
-		} `json:"frames"`
-	}{}
-
-	aartFile.Version = "1.0"
-	aartFile.Metadata.Title = filename
-	aartFile.Metadata.Created = time.Now().Format(time.RFC3339)
-	aartFile.Metadata.Modified = time.Now().Format(time.RFC3339)
+// ToAartFile converts frames to the .aart file structure
+func ToAartFile(frames []*Frame, title string) *fileformat.AartFile {
+	aartFile := fileformat.NewAartFile(frames[0].Width, frames[0].Height, title)
 	aartFile.Metadata.Source = "converted"
-	
-	aartFile.Canvas.Width = frames[0].Width
-	aartFile.Canvas.Height = frames[0].Height
-	
-	// Convert frames
-	aartFile.Frames = make([]struct {
-		Index    int `json:"index"`
-		Duration int `json:"duration"`
-		Cells    [][]struct {
-			Char       string `json:"char"`
-			Foreground string `json:"fg"`
-			Background string `json:"bg"`
-		} `json:"cells"`
-	}, len(frames))
-
-	for i, frame := range frames {
-		aartFile.Frames[i].Index = i
-		aartFile.Frames[i].Duration = frame.Delay
-		aartFile.Frames[i].Cells = make([][]struct {
-			Char       string `json:"char"`
-			Foreground string `json:"fg"`
-			Background string `json:"bg"`
-		}, frame.Height)
+	aartFile.Layers = nil
 
+	for _, frame := range frames {
+		cells := make([][]fileformat.Cell, frame.Height)
 		for y := 0; y < frame.Height; y++ {
-			aartFile.Frames[i].Cells[y] = make([]struct {
-				Char       string `json:"char"`
-				Foreground string `json:"fg"`
-				Background string `json:"bg"`
-			}, frame.Width)
-			
+			cells[y] = make([]fileformat.Cell, frame.Width)
 			for x := 0; x < frame.Width; x++ {
 				cell := frame.Cells[y][x]
-				aartFile.Frames[i].Cells[y][x].Char = string(cell.Char)
-				aartFile.Frames[i].Cells[y][x].Foreground = cell.FG
-				aartFile.Frames[i].Cells[y][x].Background = cell.BG
+				cells[y][x] = fileformat.Cell{
+					Char:       string(cell.Char),
+					Foreground: cell.FG,
+					Background: cell.BG,
+				}
 			}
 		}
+		aartFile.AddFrame(cells, frame.Delay)
 	}
+	return aartFile
+}
 
-	// Marshal to JSON
-	data, err := json.MarshalIndent(aartFile, "", "  ")
-	if err != nil {
-		return fmt.Errorf("failed to marshal frames: %w", err)
+// SaveFrames saves frames to .aart format. A .aartz extension selects the
+// compact packed container.
+func SaveFrames(frames []*Frame, filename string) error {
+	return SaveFramesWithOptions(frames, filename, fileformat.SaveOptions{Compression: fileformat.CompressionGzip})
+}
+
+// SaveFramesWithOptions saves frames with explicit container and keyframe
+// settings
+func SaveFramesWithOptions(frames []*Frame, filename string, opts fileformat.SaveOptions) error {
+	if len(frames) == 0 {
+		return fmt.Errorf("no frames to save")
 	}
 
-	// Write to file
-	if err := os.WriteFile(filename, data, 0644); err != nil {
-		return fmt.Errorf("failed to write file: %w", err)
+	if err := fileformat.SaveWithOptions(filename, ToAartFile(frames, filename), opts); err != nil {
+		return err
 	}
 
 	fmt.Printf("Saved to: %s\n", filename)
@@ -0,0 +1,175 @@
+package converter
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mlamkadm/aart/internal/fileformat"
+)
+
+// Dithering modes of Options.Dither
+const (
+	DitherNone           = "none"
+	DitherFloydSteinberg = "floyd-steinberg"
+	DitherAtkinson       = "atkinson"
+	DitherBayer4         = "bayer4"
+	DitherBayer8         = "bayer8"
+)
+
+// ParseDither accepts the names of the dithering modes and a few aliases,
+// "ordered" being the 4x4 Bayer matrix
+func ParseDither(s string) (string, error) {
+	switch strings.ToLower(s) {
+	case "", "none", "off":
+		return DitherNone, nil
+	case "floyd-steinberg", "floyd", "fs":
+		return DitherFloydSteinberg, nil
+	case "atkinson":
+		return DitherAtkinson, nil
+	case "bayer4", "bayer", "ordered":
+		return DitherBayer4, nil
+	case "bayer8":
+		return DitherBayer8, nil
+	}
+	return "", fmt.Errorf("unknown dithering %q (none, floyd-steinberg, atkinson, bayer4, bayer8)", s)
+}
+
+// bayer4 is the 4x4 ordered dithering matrix
+var bayer4 = [][]int{
+	{0, 8, 2, 10},
+	{12, 4, 14, 6},
+	{3, 11, 1, 9},
+	{15, 7, 13, 5},
+}
+
+// bayer8 is the 8x8 ordered dithering matrix
+var bayer8 = [][]int{
+	{0, 32, 8, 40, 2, 34, 10, 42},
+	{48, 16, 56, 24, 50, 18, 58, 26},
+	{12, 44, 4, 36, 14, 46, 6, 38},
+	{60, 28, 52, 20, 62, 30, 54, 22},
+	{3, 35, 11, 43, 1, 33, 9, 41},
+	{51, 19, 59, 27, 49, 17, 57, 25},
+	{15, 47, 7, 39, 13, 45, 5, 37},
+	{63, 31, 55, 23, 61, 29, 53, 21},
+}
+
+// diffusion is a share of the quantization error passed to the pixel at
+// dx, dy from the current one
+type diffusion struct {
+	dx, dy int
+	weight float64
+}
+
+var (
+	floydSteinberg = []diffusion{
+		{1, 0, 7.0 / 16}, {-1, 1, 3.0 / 16}, {0, 1, 5.0 / 16}, {1, 1, 1.0 / 16},
+	}
+	// Atkinson passes on only 6/8 of the error, which keeps contrast
+	atkinson = []diffusion{
+		{1, 0, 1.0 / 8}, {2, 0, 1.0 / 8}, {-1, 1, 1.0 / 8},
+		{0, 1, 1.0 / 8}, {1, 1, 1.0 / 8}, {0, 2, 1.0 / 8},
+	}
+)
+
+// dither quantizes every opaque pixel of the grid with quantize. Error
+// diffusion passes what quantize changed on to the following values;
+// ordered dithering offsets each value by up to half of spread, the
+// distance between neighboring levels, before quantizing it.
+func (g *pixelGrid) dither(mode string, spread float64, quantize func([3]float64) [3]float64) {
+	var kernel []diffusion
+	var matrix [][]int
+	switch mode {
+	case DitherFloydSteinberg:
+		kernel = floydSteinberg
+	case DitherAtkinson:
+		kernel = atkinson
+	case DitherBayer4:
+		matrix = bayer4
+	case DitherBayer8:
+		matrix = bayer8
+	}
+
+	for y := 0; y < g.height; y++ {
+		for x := 0; x < g.width; x++ {
+			i := y*g.width + x
+			if !g.opaque[i] {
+				continue
+			}
+			v := g.pix[i]
+			if matrix != nil {
+				n := len(matrix)
+				offset := (float64(matrix[y%n][x%n])+0.5)/float64(n*n) - 0.5
+				for c := range v {
+					v[c] += offset * spread
+				}
+			}
+			// Errors pushing past black or white are lost rather than
+			// piling up
+			for c := range v {
+				v[c] = clampChannel(v[c])
+			}
+			q := quantize(v)
+			g.pix[i] = q
+			for _, k := range kernel {
+				nx, ny := x+k.dx, y+k.dy
+				if nx < 0 || nx >= g.width || ny >= g.height || !g.opaque[ny*g.width+nx] {
+					continue
+				}
+				for c := range v {
+					g.pix[ny*g.width+nx][c] += (v[c] - q[c]) * k.weight
+				}
+			}
+		}
+	}
+}
+
+// ditherColors quantizes the colors of a grid to the ones cellColor writes
+// them as, with opts.Dither. Full colors are left alone.
+func ditherColors(g *pixelGrid, opts Options) {
+	mode := opts.ditherMode()
+	if mode == DitherNone || (opts.UseColors && opts.Palette != fileformat.Color256 && opts.Palette != fileformat.Color16) {
+		return
+	}
+	switch {
+	case !opts.UseColors:
+		// The 16 gray levels, nearest rather than rounded down
+		g.dither(mode, 17, func(v [3]float64) [3]float64 {
+			level := float64(int(clampChannel(luminosity(v))+8.5) / 17 * 17)
+			return [3]float64{level, level, level}
+		})
+	default:
+		spread := 85.0
+		if opts.Palette == fileformat.Color256 {
+			spread = 51
+		}
+		g.dither(mode, spread, func(v [3]float64) [3]float64 {
+			r, gr, b := fileformat.QuantizeColor(opts.Palette,
+				uint8(clampChannel(v[0])), uint8(clampChannel(v[1])), uint8(clampChannel(v[2])))
+			return [3]float64{float64(r), float64(gr), float64(b)}
+		})
+	}
+}
+
+// ditherMode is opts.Dither by its canonical name, DitherNone when unknown
+func (opts Options) ditherMode() string {
+	mode, err := ParseDither(opts.Dither)
+	if err != nil {
+		return DitherNone
+	}
+	return mode
+}
+
+func luminosity(v [3]float64) float64 {
+	return 0.299*v[0] + 0.587*v[1] + 0.114*v[2]
+}
+
+func clampChannel(v float64) float64 {
+	if v < 0 {
+		return 0
+	}
+	if v > 255 {
+		return 255
+	}
+	return v
+}
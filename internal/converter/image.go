@@ -0,0 +1,111 @@
+package converter
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"image"
+	"image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strings"
+
+	_ "golang.org/x/image/bmp"
+)
+
+// ImageExtensions are the file types ConvertToFrames reads
+var ImageExtensions = []string{".gif", ".png", ".jpg", ".jpeg", ".bmp"}
+
+// IsImageSource reports whether a path or URL names an image by its
+// extension
+func IsImageSource(source string) bool {
+	ext := strings.ToLower(filepath.Ext(source))
+	if isURL(source) {
+		if u, err := url.Parse(source); err == nil {
+			ext = strings.ToLower(path.Ext(u.Path))
+		}
+	}
+	for _, e := range ImageExtensions {
+		if ext == e {
+			return true
+		}
+	}
+	return false
+}
+
+// ImportImage is a convenience wrapper for UI like ImportGIF, which also
+// takes still images
+func ImportImage(source string, width, height, fps int, method, ratio string) ([]*Frame, error) {
+	return ConvertToFrames(source, Options{
+		Width:     width,
+		Height:    height,
+		FPS:       fps,
+		Method:    method,
+		Ratio:     ratio,
+		UseColors: false,
+	})
+}
+
+// ConvertToFrames converts a GIF, PNG, JPEG or BMP image (from URL or
+// file) to ASCII frames. The format is told by the content rather than
+// the name. GIFs convert like ConvertGifToFrames; other images become a
+// single frame lasting 1/FPS seconds. A directory or a glob is an image
+// sequence, see ConvertSequence.
+func ConvertToFrames(source string, opts Options) ([]*Frame, error) {
+	if IsSequenceSource(source) {
+		return ConvertSequence(source, opts)
+	}
+	if opts.ProgressCallback != nil {
+		opts.ProgressCallback(0, 100, "Loading image...")
+	}
+
+	reader, err := openSource(source)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	buffered := bufio.NewReader(reader)
+
+	if magic, _ := buffered.Peek(3); bytes.Equal(magic, []byte("GIF")) {
+		gifData, err := gif.DecodeAll(buffered)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode GIF: %w", err)
+		}
+		return convertGif(gifData, opts)
+	}
+
+	img, format, err := image.Decode(buffered)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image (supported: %s): %w", strings.Join(ImageExtensions, ", "), err)
+	}
+	if opts.ProgressCallback != nil {
+		opts.ProgressCallback(10, 100, fmt.Sprintf("Converting %s image...", strings.ToUpper(format)))
+	}
+
+	targetWidth, targetHeight := calculateDimensions(img, opts)
+	frame := convertStill(img, targetWidth, targetHeight, opts)
+
+	if opts.ProgressCallback != nil {
+		opts.ProgressCallback(100, 100, "Complete!")
+	}
+	return []*Frame{frame}, nil
+}
+
+// convertStill resizes an image to width x height cells and converts it
+// into a frame lasting 1/FPS seconds
+func convertStill(img image.Image, width, height int, opts Options) *Frame {
+	frame := convertImage(img, width, height, opts)
+	frame.Delay = frameDelay(opts)
+	return frame
+}
+
+// frameDelay is the duration of a frame at opts.FPS
+func frameDelay(opts Options) int {
+	if opts.FPS > 0 {
+		return 1000 / opts.FPS
+	}
+	return 100
+}
@@ -0,0 +1,227 @@
+package converter
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// IsSequenceSource reports whether source names an image sequence: a
+// directory, or a glob such as "render/frame_*.png". A file that exists
+// is never a glob, even with "[" in its name.
+func IsSequenceSource(source string) bool {
+	if isURL(source) {
+		return false
+	}
+	info, err := os.Stat(source)
+	if err == nil {
+		return info.IsDir()
+	}
+	return os.IsNotExist(err) && strings.ContainsAny(source, "*?[")
+}
+
+// SequenceFiles lists the frames of an image sequence in natural order,
+// so frame_2.png comes before frame_10.png: the images in a directory, or
+// the files matching a glob. A source that is a file is its only frame.
+func SequenceFiles(source string) ([]string, error) {
+	var files []string
+	info, err := os.Stat(source)
+	switch {
+	case err == nil && !info.IsDir():
+		files = append(files, source)
+	case err == nil:
+		entries, err := os.ReadDir(source)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			name := entry.Name()
+			if !entry.IsDir() && !strings.HasPrefix(name, ".") && IsImageSource(name) {
+				files = append(files, filepath.Join(source, name))
+			}
+		}
+	default:
+		matches, err := filepath.Glob(source)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", source, err)
+		}
+		for _, match := range matches {
+			if info, err := os.Stat(match); err == nil && !info.IsDir() {
+				files = append(files, match)
+			}
+		}
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no images found in %s", source)
+	}
+	sort.SliceStable(files, func(i, j int) bool {
+		return naturalLess(filepath.Base(files[i]), filepath.Base(files[j]))
+	})
+	return files, nil
+}
+
+// ConvertSequence converts every image of a sequence (see SequenceFiles)
+// into one frame. All frames get the size the first image converts to.
+// Frames last 1/FPS seconds, or as long as opts.Durations says.
+func ConvertSequence(source string, opts Options) ([]*Frame, error) {
+	files, err := SequenceFiles(source)
+	if err != nil {
+		return nil, err
+	}
+	durations := map[string]int{}
+	if opts.Durations != "" {
+		if durations, err = loadDurations(opts.Durations, files); err != nil {
+			return nil, err
+		}
+	}
+
+	frames := make([]*Frame, len(files))
+	width, height := 0, 0
+	for i, file := range files {
+		if opts.ProgressCallback != nil {
+			opts.ProgressCallback(i*100/len(files), 100, fmt.Sprintf("Converting frame %d/%d (%s)...", i+1, len(files), filepath.Base(file)))
+		}
+		img, err := loadImage(file)
+		if err != nil {
+			return nil, err
+		}
+		if i == 0 {
+			width, height = calculateDimensions(img, opts)
+		}
+		frames[i] = convertStill(img, width, height, opts)
+		if d, ok := durations[file]; ok {
+			frames[i].Delay = d
+		}
+	}
+
+	if opts.ProgressCallback != nil {
+		opts.ProgressCallback(100, 100, "Complete!")
+	}
+	return frames, nil
+}
+
+// loadImage decodes a local image file
+func loadImage(path string) (image.Image, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+	img, _, err := image.Decode(bufio.NewReader(file))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", path, err)
+	}
+	return img, nil
+}
+
+// loadDurations reads a durations sidecar. Each line is "<file> <ms>" for
+// a file by its path relative to the sidecar, or just "<ms>" for the next
+// file in order; "ms" may follow the number and "#" starts a comment. A
+// bare file name also matches when only one of the files has it. The
+// result is keyed by the entries of files.
+func loadDurations(path string, files []string) (map[string]int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read durations: %w", err)
+	}
+	byPath := map[string]string{}
+	byName := map[string][]string{}
+	for _, file := range files {
+		if abs, err := filepath.Abs(file); err == nil {
+			byPath[abs] = file
+		}
+		byName[filepath.Base(file)] = append(byName[filepath.Base(file)], file)
+	}
+	dir := filepath.Dir(path)
+	resolve := func(name string) (string, error) {
+		p := name
+		if !filepath.IsAbs(p) {
+			p = filepath.Join(dir, p)
+		}
+		if abs, err := filepath.Abs(p); err == nil {
+			if file, ok := byPath[abs]; ok {
+				return file, nil
+			}
+		}
+		if matches := byName[name]; filepath.Base(name) == name && len(matches) > 0 {
+			if len(matches) > 1 {
+				return "", fmt.Errorf("%s names %d of the images, give its path relative to %s", name, len(matches), dir)
+			}
+			return matches[0], nil
+		}
+		return "", fmt.Errorf("%s is not one of the images", name)
+	}
+
+	durations := map[string]int{}
+	next := 0
+	for n, line := range strings.Split(string(data), "\n") {
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) > 2 {
+			return nil, fmt.Errorf("%s:%d: expected \"<file> <ms>\" or \"<ms>\"", path, n+1)
+		}
+		ms, err := strconv.Atoi(strings.TrimSuffix(fields[len(fields)-1], "ms"))
+		if err != nil || ms <= 0 {
+			return nil, fmt.Errorf("%s:%d: invalid duration %q", path, n+1, fields[len(fields)-1])
+		}
+		if len(fields) == 2 {
+			file, err := resolve(fields[0])
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: %w", path, n+1, err)
+			}
+			durations[file] = ms
+			continue
+		}
+		if next >= len(files) {
+			return nil, fmt.Errorf("%s:%d: more durations than the %d images", path, n+1, len(files))
+		}
+		durations[files[next]] = ms
+		next++
+	}
+	return durations, nil
+}
+
+// naturalLess compares names with runs of digits compared as numbers
+func naturalLess(a, b string) bool {
+	for a != "" && b != "" {
+		da, db := digitPrefix(a), digitPrefix(b)
+		if da > 0 && db > 0 {
+			na, nb := strings.TrimLeft(a[:da], "0"), strings.TrimLeft(b[:db], "0")
+			if len(na) != len(nb) {
+				return len(na) < len(nb)
+			}
+			if na != nb {
+				return na < nb
+			}
+			if da != db {
+				return da < db // fewer leading zeros first
+			}
+			a, b = a[da:], b[db:]
+			continue
+		}
+		if a[0] != b[0] {
+			return a[0] < b[0]
+		}
+		a, b = a[1:], b[1:]
+	}
+	return len(a) < len(b)
+}
+
+// digitPrefix returns the length of the run of digits s starts with
+func digitPrefix(s string) int {
+	n := 0
+	for n < len(s) && s[n] >= '0' && s[n] <= '9' {
+		n++
+	}
+	return n
+}
@@ -1,9 +1,12 @@
 package fileformat
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 	"time"
 )
 
@@ -37,10 +40,13 @@ type Canvas struct {
 
 // Frame represents a single animation frame
 type Frame struct {
-	Index    int      `json:"index"`
-	Duration int      `json:"duration"` // milliseconds
-	Cells    [][]Cell `json:"cells"`
-	Name     string   `json:"name,omitempty"`
+	Index    int          `json:"index"`
+	Duration int          `json:"duration"` // milliseconds
+	Cells    [][]Cell     `json:"cells"`    // composited view of all layers
+	Name     string       `json:"name,omitempty"`
+	Layers   []FrameLayer `json:"layers,omitempty"` // per-layer cells, see AartFile.Layers
+	Encoding string       `json:"encoding,omitempty"` // "" (keyframe) or "delta"
+	Delta    []CellDelta  `json:"delta,omitempty"`    // changed cells of a delta frame
 }
 
 // Cell represents a single character cell
@@ -74,28 +80,136 @@ type Audio struct {
 	Loop   bool   `json:"loop"`
 }
 
-// Load loads a .aart file
+// Load loads a .aart file. Packed containers are detected by their magic
+// bytes and decoded in full; use OpenPacked to read frames on demand.
+// Files from older versions are migrated to CurrentVersion.
 func Load(path string) (*AartFile, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read file: %w", err)
 	}
 
-	var aart AartFile
-	if err := json.Unmarshal(data, &aart); err != nil {
+	if IsPacked(data) {
+		p, err := NewPackedReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse packed .aart file: %w", err)
+		}
+		return p.ReadAll()
+	}
+
+	aart, err := decodeJSON(data)
+	if err != nil {
 		return nil, fmt.Errorf("failed to parse .aart file: %w", err)
 	}
+	if err := aart.DecodeDeltas(); err != nil {
+		return nil, fmt.Errorf("failed to decode delta frames: %w", err)
+	}
+
+	return aart, nil
+}
+
+// FrameSource reads the frames of a file one at a time. PackedReader
+// decodes them on demand; NewFrameSource serves a file already loaded.
+type FrameSource interface {
+	Header() *AartFile // the file; frames may come without cells
+	FrameCount() int
+	Frame(i int) (*Frame, error)
+	Close() error
+}
+
+// Open opens a file for reading frame by frame: a packed container through
+// a PackedReader, so only the frames asked for are decoded, any other file
+// through Load
+func Open(path string) (FrameSource, error) {
+	if p, err := OpenPacked(path); err == nil {
+		return p, nil
+	}
+	aart, err := Load(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewFrameSource(aart), nil
+}
+
+// NewFrameSource serves the frames of a loaded file
+func NewFrameSource(a *AartFile) FrameSource {
+	return loadedFile{a}
+}
+
+type loadedFile struct {
+	*AartFile
+}
+
+func (f loadedFile) Header() *AartFile {
+	return f.AartFile
+}
+
+func (f loadedFile) Frame(i int) (*Frame, error) {
+	if i < 0 || i >= len(f.Frames) {
+		return nil, fmt.Errorf("frame %d out of range", i)
+	}
+	return &f.Frames[i], nil
+}
+
+func (f loadedFile) Close() error {
+	return nil
+}
+
+// Container selects how a .aart file is stored on disk
+type Container string
+
+const (
+	ContainerJSON   Container = "json"   // pretty-printed JSON
+	ContainerPacked Container = "packed" // compact binary, see packed.go
+)
+
+// SaveOptions controls how Save writes a file
+type SaveOptions struct {
+	Container        Container   // empty picks the container from the extension
+	Compression      Compression // packed container only
+	KeyframeInterval int         // >0 stores frames between keyframes as deltas
+}
 
-	return &aart, nil
+// ContainerForPath picks the container from a file extension
+func ContainerForPath(path string) Container {
+	if strings.EqualFold(filepath.Ext(path), PackedExt) {
+		return ContainerPacked
+	}
+	return ContainerJSON
 }
 
-// Save saves a .aart file
+// Save saves a .aart file, packed if the path ends in .aartz
 func Save(path string, aart *AartFile) error {
+	return SaveWithOptions(path, aart, SaveOptions{Compression: CompressionGzip})
+}
+
+// SaveWithOptions saves a .aart file in the requested container
+func SaveWithOptions(path string, aart *AartFile, opts SaveOptions) error {
 	// Update modified timestamp
 	aart.Metadata.Modified = time.Now()
+	aart.Version = CurrentVersion
+
+	// Keep the flat cells in sync with the layer data
+	aart.Flatten()
 
-	// Pretty print JSON
-	data, err := json.MarshalIndent(aart, "", "  ")
+	container := opts.Container
+	if container == "" {
+		container = ContainerForPath(path)
+	}
+
+	out := aart.EncodeDeltas(opts.KeyframeInterval)
+
+	var data []byte
+	var err error
+	switch container {
+	case ContainerPacked:
+		data, err = encodePacked(out, opts.Compression)
+	case ContainerJSON:
+		// Pretty print JSON
+		data, err = json.MarshalIndent(out, "", "  ")
+	default:
+		return fmt.Errorf("unknown container %q", container)
+	}
 	if err != nil {
 		return fmt.Errorf("failed to marshal .aart file: %w", err)
 	}
@@ -111,7 +225,7 @@ func Save(path string, aart *AartFile) error {
 func NewAartFile(width, height int, title string) *AartFile {
 	now := time.Now()
 	return &AartFile{
-		Version: "1.0",
+		Version: CurrentVersion,
 		Metadata: Metadata{
 			Title:    title,
 			Created:  now,
@@ -168,6 +282,9 @@ func (a *AartFile) Validate() error {
 	}
 
 	for i, frame := range a.Frames {
+		if len(frame.Layers) > 0 && len(frame.Layers) != len(a.Layers) {
+			return fmt.Errorf("frame %d: has %d layers, expected %d", i, len(frame.Layers), len(a.Layers))
+		}
 		if len(frame.Cells) != a.Canvas.Height {
 			return fmt.Errorf("frame %d: wrong height %d, expected %d", i, len(frame.Cells), a.Canvas.Height)
 		}
@@ -0,0 +1,335 @@
+package fileformat
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ColorMode selects how colors are written as ANSI escape sequences
+type ColorMode string
+
+const (
+	ColorTrue ColorMode = "truecolor" // 24-bit, 38;2;r;g;b
+	Color256  ColorMode = "256"       // xterm-256 nearest match, 38;5;n
+	Color16   ColorMode = "16"        // the basic and bright colors, 30-37/90-97
+	ColorNone ColorMode = "none"      // styles only
+)
+
+// ParseColorMode accepts the names of the color modes and a few aliases
+func ParseColorMode(s string) (ColorMode, error) {
+	switch strings.ToLower(s) {
+	case "", "truecolor", "24bit", "24-bit", "rgb":
+		return ColorTrue, nil
+	case "256", "xterm256", "xterm-256":
+		return Color256, nil
+	case "16", "ansi16":
+		return Color16, nil
+	case "none", "mono", "off":
+		return ColorNone, nil
+	}
+	return "", fmt.Errorf("unknown color mode %q (truecolor, 256, 16, none)", s)
+}
+
+// colorMode is the mode an export writes colors in
+func (opts ExportOptions) colorMode() ColorMode {
+	if !opts.Colors {
+		return ColorNone
+	}
+	if opts.ColorMode == "" {
+		return ColorTrue
+	}
+	return opts.ColorMode
+}
+
+// exportANSI writes frames with ANSI escape sequences. A single frame is
+// written row by row. Several frames are written one after the other,
+// each starting with cursor-home and redrawing the whole canvas, so
+// `cat` ends on the last frame; PlayerScriptPath(path) gets a shell
+// script that plays the file with the frame durations.
+func exportANSI(aart *AartFile, path string, opts ExportOptions) error {
+	start, end := frameRange(aart, opts)
+	if start >= end {
+		return fmt.Errorf("no frames to export")
+	}
+	width, height := aart.Canvas.Width, aart.Canvas.Height
+	enc := newScreenEncoder(width, height, opts.colorMode())
+
+	var out strings.Builder
+	if end-start == 1 {
+		enc.writeFrame(&out, aart.Frames[start].Cells, "\n")
+		out.WriteString("\n")
+		return os.WriteFile(path, []byte(out.String()), 0644)
+	}
+
+	out.WriteString("\x1b[?25l\x1b[0m\x1b[2J")
+	type chunk struct{ offset, length, duration int }
+	var chunks []chunk
+	for i := start; i < end; i++ {
+		offset := out.Len()
+		out.WriteString("\x1b[H")
+		// Rows are joined with CR LF so nothing scrolls, even when the
+		// canvas is as tall as the terminal
+		enc.writeFrame(&out, aart.Frames[i].Cells, "\r\n")
+		chunks = append(chunks, chunk{offset, out.Len() - offset, max(aart.Frames[i].Duration, MinDuration)})
+	}
+	out.WriteString("\x1b[?25h\n")
+	if err := os.WriteFile(path, []byte(out.String()), 0644); err != nil {
+		return err
+	}
+
+	var script strings.Builder
+	script.WriteString("#!/bin/sh\n")
+	script.WriteString(fmt.Sprintf("# Plays %s with its frame durations. Generated by aart.\n", filepath.Base(path)))
+	script.WriteString("# Usage: sh " + filepath.Base(PlayerScriptPath(path)) + " [--once]\n")
+	script.WriteString("file=\"$(dirname \"$0\")\"/" + shellQuote(filepath.Base(path)) + "\n")
+	script.WriteString("trap 'printf \"\\033[0m\\033[?25h\\n\"; exit 0' INT TERM\n")
+	script.WriteString("printf '\\033[?25l\\033[0m\\033[2J'\n")
+	script.WriteString("while :; do\n")
+	for _, c := range chunks {
+		script.WriteString(fmt.Sprintf("\ttail -c +%d \"$file\" | head -c %d; sleep %s\n",
+			c.offset+1, c.length, strconv.FormatFloat(float64(c.duration)/1000, 'f', 3, 64)))
+	}
+	script.WriteString("\t[ \"$1\" = --once ] && break\n")
+	script.WriteString("done\n")
+	script.WriteString("printf '\\033[0m\\033[?25h\\n'\n")
+	return os.WriteFile(PlayerScriptPath(path), []byte(script.String()), 0755)
+}
+
+// PlayerScriptPath returns where the player script of an animated ANSI
+// export is written
+func PlayerScriptPath(ansiPath string) string {
+	return strings.TrimSuffix(ansiPath, filepath.Ext(ansiPath)) + ".sh"
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// screenEncoder writes the escape sequences that draw frames on a
+// terminal. It tracks the cursor and the current style, so it only moves
+// the cursor to cells that changed and only sends SGR when the style
+// changes.
+type screenEncoder struct {
+	width, height int
+	mode          ColorMode
+
+	x, y   int // cursor, x < 0 when unknown
+	styled bool
+	style  Cell // last style sent, Char unused
+
+	quantized map[string]string // color -> the palette color it is sent as
+}
+
+func newScreenEncoder(width, height int, mode ColorMode) *screenEncoder {
+	return &screenEncoder{width: width, height: height, mode: mode, x: -1, quantized: make(map[string]string)}
+}
+
+// clear resets the style, clears the screen and homes the cursor. The
+// screen is then all blankCell.
+func (e *screenEncoder) clear(b *strings.Builder) {
+	b.WriteString("\x1b[0m\x1b[2J\x1b[H")
+	e.x, e.y = 0, 0
+	e.styled, e.style = true, Cell{}
+}
+
+// writeFrame writes every cell of a frame row by row, separating rows with
+// sep and resetting the style at the end of each row so backgrounds do
+// not bleed past the canvas
+func (e *screenEncoder) writeFrame(b *strings.Builder, cells [][]Cell, sep string) {
+	e.styled = false
+	for y := 0; y < e.height; y++ {
+		if y > 0 {
+			b.WriteString(sep)
+		}
+		for x := 0; x < e.width; x++ {
+			e.writeCell(b, cellAt(cells, x, y))
+		}
+		if e.styled && e.style != (Cell{}) {
+			b.WriteString("\x1b[0m")
+			e.style = Cell{}
+		}
+	}
+	e.x = -1
+}
+
+// diff writes the changes from prev to next. A nil prev is a cleared
+// screen.
+func (e *screenEncoder) diff(b *strings.Builder, prev, next [][]Cell) {
+	for y := 0; y < e.height; y++ {
+		for x := 0; x < e.width; x++ {
+			old, cur := cellAt(prev, x, y), cellAt(next, x, y)
+			if e.same(old, cur) {
+				continue
+			}
+			e.moveTo(b, x, y)
+			e.writeCell(b, cur)
+			e.x++
+			if e.x >= e.width {
+				// The cursor waits in the last column; do not rely on it
+				e.x = -1
+			}
+		}
+	}
+}
+
+func (e *screenEncoder) writeCell(b *strings.Builder, c Cell) {
+	e.setStyle(b, c)
+	if c.Char == "" {
+		b.WriteByte(' ')
+	} else {
+		b.WriteString(c.Char)
+	}
+}
+
+func (e *screenEncoder) moveTo(b *strings.Builder, x, y int) {
+	switch {
+	case e.x == x && e.y == y:
+		return
+	case e.x >= 0 && e.y == y && x > e.x:
+		if n := x - e.x; n == 1 {
+			b.WriteString("\x1b[C")
+		} else {
+			b.WriteString("\x1b[" + strconv.Itoa(n) + "C")
+		}
+	case e.x >= 0 && x == 0 && y == e.y+1:
+		b.WriteString("\r\n")
+	default:
+		b.WriteString("\x1b[" + strconv.Itoa(y+1) + ";" + strconv.Itoa(x+1) + "H")
+	}
+	e.x, e.y = x, y
+}
+
+func (e *screenEncoder) setStyle(b *strings.Builder, c Cell) {
+	c = e.key(c)
+	if e.styled && c == e.style {
+		return
+	}
+	b.WriteString(sgr(c, e.mode))
+	e.styled, e.style = true, c
+}
+
+// key is the style of a cell as the terminal will show it: no character,
+// terminal default colors as "", colors reduced to the mode's palette, and
+// none at all in ColorNone. Cells with equal keys need no SGR between them.
+func (e *screenEncoder) key(c Cell) Cell {
+	c.Char = ""
+	if e.mode == ColorNone {
+		c.Foreground, c.Background = "", ""
+		return c
+	}
+	c.Foreground, c.Background = e.quantize(terminalFG(c.Foreground)), e.quantize(terminalBG(c.Background))
+	return c
+}
+
+func (e *screenEncoder) quantize(hex string) string {
+	if hex == "" || e.mode == ColorTrue {
+		return hex
+	}
+	if q, ok := e.quantized[hex]; ok {
+		return q
+	}
+	q := ""
+	if r, g, b, ok := ParseHexColor(hex); ok {
+		if e.mode == Color256 {
+			r, g, b = xterm256(NearestXterm256(r, g, b))
+		} else {
+			c := ansi16[NearestANSI16(r, g, b)]
+			r, g, b = c[0], c[1], c[2]
+		}
+		q = FormatHexColor(r, g, b)
+	}
+	e.quantized[hex] = q
+	return q
+}
+
+// same reports whether two cells look the same on the terminal
+func (e *screenEncoder) same(a, b Cell) bool {
+	if a.Char == "" {
+		a.Char = " "
+	}
+	if b.Char == "" {
+		b.Char = " "
+	}
+	return a.Char == b.Char && e.key(a) == e.key(b)
+}
+
+// sgr returns the full SGR sequence for a style, starting from a reset.
+// Empty colors are the terminal defaults.
+func sgr(c Cell, mode ColorMode) string {
+	var b strings.Builder
+	b.WriteString("\x1b[0")
+	if c.Bold {
+		b.WriteString(";1")
+	}
+	if c.Italic {
+		b.WriteString(";3")
+	}
+	if c.Underline {
+		b.WriteString(";4")
+	}
+	b.WriteString(sgrColor(c.Foreground, mode, false))
+	b.WriteString(sgrColor(c.Background, mode, true))
+	b.WriteByte('m')
+	return b.String()
+}
+
+// sgrColor returns the parameters that set a color, with a leading ";",
+// or "" for the default color
+func sgrColor(hex string, mode ColorMode, background bool) string {
+	r, g, b, ok := ParseHexColor(hex)
+	if !ok || mode == ColorNone {
+		return ""
+	}
+	switch mode {
+	case Color256:
+		if background {
+			return ";48;5;" + strconv.Itoa(int(NearestXterm256(r, g, b)))
+		}
+		return ";38;5;" + strconv.Itoa(int(NearestXterm256(r, g, b)))
+	case Color16:
+		n := int(NearestANSI16(r, g, b))
+		base := 30
+		if n >= 8 {
+			base, n = 90, n-8
+		}
+		if background {
+			base += 10
+		}
+		return ";" + strconv.Itoa(base+n)
+	}
+	if background {
+		return ";48;2;" + rgbParams(r, g, b)
+	}
+	return ";38;2;" + rgbParams(r, g, b)
+}
+
+func rgbParams(r, g, b uint8) string {
+	return strconv.Itoa(int(r)) + ";" + strconv.Itoa(int(g)) + ";" + strconv.Itoa(int(b))
+}
+
+// terminalFG and terminalBG map the colors terminals start with to "", so
+// they are left to the terminal's theme
+func terminalFG(hex string) string {
+	if strings.EqualFold(hex, blankCell.Foreground) {
+		return ""
+	}
+	return hex
+}
+
+func terminalBG(hex string) string {
+	if strings.EqualFold(hex, blankCell.Background) {
+		return ""
+	}
+	return hex
+}
+
+// cellAt returns a cell of a grid, or blankCell outside it
+func cellAt(cells [][]Cell, x, y int) Cell {
+	if y < len(cells) && x < len(cells[y]) {
+		return cells[y][x]
+	}
+	return blankCell
+}
@@ -0,0 +1,236 @@
+package fileformat
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// castHeader is the first line of an asciicast v2 recording
+type castHeader struct {
+	Version       int               `json:"version"`
+	Width         int               `json:"width"`
+	Height        int               `json:"height"`
+	Timestamp     int64             `json:"timestamp,omitempty"`
+	Duration      float64           `json:"duration,omitempty"`
+	IdleTimeLimit float64           `json:"idle_time_limit,omitempty"`
+	Title         string            `json:"title,omitempty"`
+	Env           map[string]string `json:"env,omitempty"`
+}
+
+// exportAsciicast writes an asciicast v2 recording for asciinema. The
+// first event draws the first frame; every later frame is an event at its
+// start time holding only the escape sequences for the cells that changed.
+func exportAsciicast(aart *AartFile, path string, opts ExportOptions) error {
+	start, end := frameRange(aart, opts)
+	if start >= end {
+		return fmt.Errorf("no frames to export")
+	}
+	width, height := aart.Canvas.Width, aart.Canvas.Height
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+
+	header := castHeader{
+		Version: 2,
+		Width:   width,
+		Height:  height,
+		Title:   aart.Metadata.Title,
+		Env:     map[string]string{"TERM": "xterm-256color"},
+	}
+	if !aart.Metadata.Created.IsZero() {
+		header.Timestamp = aart.Metadata.Created.Unix()
+	}
+	if err := enc.Encode(header); err != nil {
+		return err
+	}
+
+	screen := newScreenEncoder(width, height, opts.colorMode())
+	var prev [][]Cell
+	elapsed := 0
+	for i := start; i < end; i++ {
+		frame := &aart.Frames[i]
+		var out strings.Builder
+		if i == start {
+			out.WriteString("\x1b[?25l") // hide the cursor
+			screen.clear(&out)
+		}
+		screen.diff(&out, prev, frame.Cells)
+		if out.Len() > 0 {
+			if err := enc.Encode([]any{castTime(elapsed), "o", out.String()}); err != nil {
+				return err
+			}
+		}
+		prev = frame.Cells
+		elapsed += max(frame.Duration, MinDuration)
+	}
+	// Hold the last frame for its duration
+	if err := enc.Encode([]any{castTime(elapsed), "o", "\x1b[0m"}); err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, buf.Bytes(), 0644)
+}
+
+// castTime converts milliseconds to event seconds
+func castTime(ms int) float64 {
+	return float64(ms) / 1000
+}
+
+// CastImportOptions controls how a recording is sampled into frames
+type CastImportOptions struct {
+	FPS    int // samples per second (0 = 12)
+	Width  int // canvas width (0 = the recording's)
+	Height int // canvas height (0 = the recording's)
+}
+
+// ImportAsciicast replays an asciicast v2 recording through a terminal
+// emulator and samples the screen into frames. Output is grouped into
+// slots of 1/FPS seconds; the screen after each slot becomes a frame
+// starting at the slot's first event, unless it did not change. Pauses
+// longer than the recording's idle_time_limit are shortened to it.
+func ImportAsciicast(path string, opts CastImportOptions) (*AartFile, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	if !scanner.Scan() {
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%s: empty recording", path)
+	}
+	var header castHeader
+	if err := json.Unmarshal(scanner.Bytes(), &header); err != nil {
+		return nil, fmt.Errorf("%s: invalid asciicast header: %w", path, err)
+	}
+	if header.Version != 2 {
+		return nil, fmt.Errorf("%s: unsupported asciicast version %d (only 2 is supported)", path, header.Version)
+	}
+	if header.Width <= 0 || header.Height <= 0 {
+		return nil, fmt.Errorf("%s: invalid terminal size %dx%d", path, header.Width, header.Height)
+	}
+
+	fps := opts.FPS
+	if fps <= 0 {
+		fps = 12
+	}
+	slot := 1000 / float64(fps)
+	width, height := header.Width, header.Height
+	if opts.Width > 0 {
+		width = opts.Width
+	}
+	if opts.Height > 0 {
+		height = opts.Height
+	}
+
+	term := newTerminal(header.Width, header.Height)
+	var frames [][][]Cell
+	var starts []float64
+	pending := -1.0 // start of the slot being collected, in ms
+	flush := func() {
+		if pending < 0 {
+			return
+		}
+		cells := fitCells(term.Screen(), width, height)
+		unchanged := false
+		if len(frames) > 0 {
+			delta, ok := DiffGrid(frames[len(frames)-1], cells)
+			unchanged = ok && len(delta) == 0
+		}
+		if !unchanged {
+			frames = append(frames, cells)
+			starts = append(starts, pending)
+		}
+		pending = -1
+	}
+
+	now, last := 0.0, 0.0 // ms on the output timeline, seconds in the recording
+	for line := 2; scanner.Scan(); line++ {
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+		var event [3]any
+		if err := json.Unmarshal(text, &event); err != nil {
+			return nil, fmt.Errorf("%s:%d: invalid event: %w", path, line, err)
+		}
+		t, ok1 := event[0].(float64)
+		kind, ok2 := event[1].(string)
+		data, ok3 := event[2].(string)
+		if !ok1 || !ok2 || !ok3 {
+			return nil, fmt.Errorf("%s:%d: invalid event, expected [time, type, data]", path, line)
+		}
+
+		gap := math.Max(t-last, 0)
+		if header.IdleTimeLimit > 0 {
+			gap = math.Min(gap, header.IdleTimeLimit)
+		}
+		now += gap * 1000
+		last = t
+		if kind != "o" {
+			continue
+		}
+
+		if pending >= 0 && math.Floor(now/slot) != math.Floor(pending/slot) {
+			flush()
+		}
+		if pending < 0 {
+			pending = now
+		}
+		term.Write([]byte(data))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	flush()
+	if len(frames) == 0 {
+		return nil, fmt.Errorf("%s: recording has no output", path)
+	}
+
+	title := header.Title
+	if title == "" {
+		title = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	aart := NewAartFile(width, height, title)
+	aart.Metadata.Source = path
+	aart.Layers = nil
+	if header.Timestamp > 0 {
+		aart.Metadata.Created = time.Unix(header.Timestamp, 0)
+	}
+	for i, cells := range frames {
+		end := now
+		if i+1 < len(starts) {
+			end = starts[i+1]
+		}
+		duration := int(math.Round(end - starts[i]))
+		if i == len(frames)-1 && duration < MinDuration {
+			duration = DefaultDuration
+		}
+		aart.AddFrame(cells, max(duration, MinDuration))
+	}
+	return aart, nil
+}
+
+// fitCells crops or pads a grid with blank cells to width x height
+func fitCells(cells [][]Cell, width, height int) [][]Cell {
+	out := make([][]Cell, height)
+	for y := range out {
+		out[y] = make([]Cell, width)
+		for x := range out[y] {
+			out[y][x] = cellAt(cells, x, y)
+		}
+	}
+	return out
+}
@@ -0,0 +1,328 @@
+package fileformat
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SAUCE record layout, see https://www.acid.org/info/sauce/sauce.htm
+const (
+	sauceSize        = 128
+	sauceCommentSize = 64
+	sauceMaxComments = 255
+
+	sauceDataCharacter = 1
+	sauceFileANSI      = 1
+	sauceFlagICE       = 1 // TFlags bit 0: blink selects bright backgrounds
+)
+
+// sauce is the metadata record appended to ANSI art files
+type sauce struct {
+	Title, Author, Group string
+	Date                 time.Time
+	FileSize             uint32
+	DataType, FileType   uint8
+	Width, Height        uint16 // TInfo1 and TInfo2 for character files
+	Flags                uint8
+	Font                 string
+	Comments             []string
+}
+
+// bbsColor is a cell as BBS ANSI draws it: a VGA foreground and
+// background, 8-15 being bright (bold and blink)
+type bbsColor struct{ fg, bg uint8 }
+
+var bbsDefault = bbsColor{fg: 7, bg: 0}
+
+// exportBBS writes one frame (the first unless one is selected) as DOS
+// ANSI art for PabloDraw, Moebius and BBSes: CP437 text, 16-color SGR with
+// iCE colors, and a SAUCE record with the title, author and size.
+func exportBBS(aart *AartFile, path string, opts ExportOptions) error {
+	if len(aart.Frames) == 0 {
+		return fmt.Errorf("no frames to export")
+	}
+	frameIdx := 0
+	if opts.FrameIndex >= 0 && opts.FrameIndex < len(aart.Frames) {
+		frameIdx = opts.FrameIndex
+	}
+	cells := aart.Frames[frameIdx].Cells
+	width, height := aart.Canvas.Width, aart.Canvas.Height
+
+	quantized := map[string]uint8{}
+	vga := func(hex string, fallback uint8) uint8 {
+		if q, ok := quantized[hex]; ok {
+			return q
+		}
+		q := fallback
+		if r, g, b, ok := ParseHexColor(hex); ok {
+			q = nearest16(&vgaPalette, r, g, b)
+		}
+		quantized[hex] = q
+		return q
+	}
+	colorOf := func(c Cell) bbsColor {
+		if !opts.Colors {
+			return bbsDefault
+		}
+		return bbsColor{fg: vga(c.Foreground, 7), bg: vga(c.Background, 0)}
+	}
+
+	var data bytes.Buffer
+	data.WriteString("\x1b[0m")
+	cur := bbsDefault
+	prevFull := false
+	for y := 0; y < height; y++ {
+		// Trailing spaces on black need not be written
+		last := width - 1
+		for ; last >= 0; last-- {
+			c := cellAt(cells, last, y)
+			if toCP437(c.Char) != ' ' || colorOf(c).bg != 0 {
+				break
+			}
+		}
+		// A full row wraps by itself, but viewers differ on whether the
+		// wrap happens before the next character; printing one settles it
+		if prevFull {
+			last = max(last, 0)
+		}
+		for x := 0; x <= last; x++ {
+			c := cellAt(cells, x, y)
+			next := colorOf(c)
+			data.WriteString(bbsSGR(cur, next))
+			cur = next
+			data.WriteByte(toCP437(c.Char))
+		}
+		prevFull = last == width-1
+		if !prevFull && y < height-1 {
+			data.WriteString("\r\n")
+		}
+	}
+	data.WriteString("\x1b[0m")
+
+	record := sauce{
+		Title:    aart.Metadata.Title,
+		Author:   aart.Metadata.Author,
+		Date:     aart.Metadata.Created,
+		FileSize: uint32(data.Len()),
+		DataType: sauceDataCharacter,
+		FileType: sauceFileANSI,
+		Width:    uint16(min(width, 0xFFFF)),
+		Height:   uint16(min(height, 0xFFFF)),
+		Flags:    sauceFlagICE,
+		Font:     "IBM VGA",
+	}
+	if record.Date.IsZero() {
+		record.Date = time.Now()
+	}
+	if aart.Metadata.Description != "" {
+		record.Comments = wrapSauceComment(aart.Metadata.Description)
+	}
+
+	data.WriteByte(0x1A) // EOF, viewers stop reading here
+	data.Write(record.encode())
+	return os.WriteFile(path, data.Bytes(), 0644)
+}
+
+// bbsSGR returns the SGR that changes the colors from cur to next, or ""
+// when they are the same. Bold and blink can only be turned off by a
+// reset, which also resets the colors.
+func bbsSGR(cur, next bbsColor) string {
+	if cur == next {
+		return ""
+	}
+	var params []string
+	if cur.fg >= 8 && next.fg < 8 || cur.bg >= 8 && next.bg < 8 {
+		params = append(params, "0")
+		cur = bbsDefault
+	}
+	if next.fg >= 8 && cur.fg < 8 {
+		params = append(params, "1")
+	}
+	if next.bg >= 8 && cur.bg < 8 {
+		params = append(params, "5")
+	}
+	if next.fg%8 != cur.fg%8 {
+		params = append(params, strconv.Itoa(30+int(next.fg%8)))
+	}
+	if next.bg%8 != cur.bg%8 {
+		params = append(params, strconv.Itoa(40+int(next.bg%8)))
+	}
+	return "\x1b[" + strings.Join(params, ";") + "m"
+}
+
+// ImportANS reads DOS ANSI art: CP437 text with ANSI escape sequences and
+// an optional SAUCE record, which gives the width (80 without one), the
+// height, iCE colors and the title and author.
+func ImportANS(path string) (*AartFile, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	data, record := splitSauce(raw)
+	if i := bytes.IndexByte(data, 0x1A); i >= 0 {
+		data = data[:i]
+	}
+
+	width, height, ice := 80, 0, false
+	if record != nil && record.DataType == sauceDataCharacter {
+		if record.Width > 0 {
+			width = int(record.Width)
+		}
+		height = int(record.Height)
+		ice = record.Flags&sauceFlagICE != 0
+	}
+
+	// Art can be taller than any screen: give the terminal room for every
+	// line the file could possibly have, then trim
+	rows := bytes.Count(data, []byte{'\n'}) + len(data)/width + 1
+	term := newArtTerminal(width, max(rows, height), ice)
+	term.Write(decodeCP437(data))
+	cells := term.Screen()
+
+	if height == 0 {
+		height = len(cells)
+		for height > 1 && isBlankRow(cells[height-1]) {
+			height--
+		}
+	}
+
+	title := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	if record != nil && record.Title != "" {
+		title = record.Title
+	}
+	aart := NewAartFile(width, height, title)
+	aart.Metadata.Source = path
+	aart.Layers = nil
+	if record != nil {
+		aart.Metadata.Author = record.Author
+		aart.Metadata.Description = strings.Join(record.Comments, "\n")
+		if !record.Date.IsZero() {
+			aart.Metadata.Created = record.Date
+		}
+	}
+	aart.AddFrame(fitCells(cells, width, height), DefaultDuration)
+	return aart, nil
+}
+
+// isBlankRow reports whether a row is only spaces on black
+func isBlankRow(row []Cell) bool {
+	for _, c := range row {
+		if c.Char != " " || c.Background != blankCell.Background {
+			return false
+		}
+	}
+	return true
+}
+
+// splitSauce separates the SAUCE record and its comments from the data.
+// The record is nil when the file has none.
+func splitSauce(raw []byte) ([]byte, *sauce) {
+	if len(raw) < sauceSize || string(raw[len(raw)-sauceSize:len(raw)-sauceSize+5]) != "SAUCE" {
+		return raw, nil
+	}
+	rec := raw[len(raw)-sauceSize:]
+	field := func(from, to int) string {
+		return strings.TrimRight(string(decodeCP437(rec[from:to])), " \x00")
+	}
+	s := &sauce{
+		Title:    field(7, 42),
+		Author:   field(42, 62),
+		Group:    field(62, 82),
+		FileSize: binary.LittleEndian.Uint32(rec[90:94]),
+		DataType: rec[94],
+		FileType: rec[95],
+		Width:    binary.LittleEndian.Uint16(rec[96:98]),
+		Height:   binary.LittleEndian.Uint16(rec[98:100]),
+		Flags:    rec[105],
+		Font:     field(106, 128),
+	}
+	if date, err := time.Parse("20060102", string(rec[82:90])); err == nil {
+		s.Date = date
+	}
+
+	end := len(raw) - sauceSize
+	if n := int(rec[104]); n > 0 {
+		start := end - 5 - n*sauceCommentSize
+		if start >= 0 && string(raw[start:start+5]) == "COMNT" {
+			for i := 0; i < n; i++ {
+				line := raw[start+5+i*sauceCommentSize : start+5+(i+1)*sauceCommentSize]
+				s.Comments = append(s.Comments, strings.TrimRight(string(decodeCP437(line)), " \x00"))
+			}
+			end = start
+		}
+	}
+	return raw[:end], s
+}
+
+// encode returns the comment block, if any, and the 128-byte record
+func (s *sauce) encode() []byte {
+	var out bytes.Buffer
+	if len(s.Comments) > 0 {
+		out.WriteString("COMNT")
+		for _, line := range s.Comments {
+			out.Write(sauceField(line, sauceCommentSize, ' '))
+		}
+	}
+	out.WriteString("SAUCE00")
+	out.Write(sauceField(s.Title, 35, ' '))
+	out.Write(sauceField(s.Author, 20, ' '))
+	out.Write(sauceField(s.Group, 20, ' '))
+	out.WriteString(s.Date.Format("20060102"))
+	binary.Write(&out, binary.LittleEndian, s.FileSize)
+	out.WriteByte(s.DataType)
+	out.WriteByte(s.FileType)
+	binary.Write(&out, binary.LittleEndian, s.Width)
+	binary.Write(&out, binary.LittleEndian, s.Height)
+	binary.Write(&out, binary.LittleEndian, uint32(0)) // TInfo3, TInfo4
+	out.WriteByte(uint8(len(s.Comments)))
+	out.WriteByte(s.Flags)
+	out.Write(sauceField(s.Font, 22, 0)) // TInfoS is zero padded
+	return out.Bytes()
+}
+
+// sauceField encodes text as CP437, cut or padded to size bytes
+func sauceField(text string, size int, pad byte) []byte {
+	field := bytes.Repeat([]byte{pad}, size)
+	i := 0
+	for _, r := range text {
+		if i == size {
+			break
+		}
+		field[i] = toCP437(string(r))
+		i++
+	}
+	return field
+}
+
+// wrapSauceComment splits text into comment lines of at most 64 columns
+func wrapSauceComment(text string) []string {
+	var lines []string
+	for _, para := range strings.Split(text, "\n") {
+		line := ""
+		for _, word := range strings.Fields(para) {
+			for len([]rune(word)) > sauceCommentSize {
+				if line != "" {
+					lines, line = append(lines, line), ""
+				}
+				r := []rune(word)
+				lines, word = append(lines, string(r[:sauceCommentSize])), string(r[sauceCommentSize:])
+			}
+			switch {
+			case line == "":
+				line = word
+			case len([]rune(line))+1+len([]rune(word)) <= sauceCommentSize:
+				line += " " + word
+			default:
+				lines, line = append(lines, line), word
+			}
+		}
+		lines = append(lines, line)
+	}
+	return lines[:min(len(lines), sauceMaxComments)]
+}
@@ -0,0 +1,307 @@
+package fileformat
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/rivo/uniseg"
+)
+
+// Frame duration bounds used when repairing files, in milliseconds
+const (
+	MinDuration     = 10
+	MaxDuration     = 60000
+	DefaultDuration = 100
+)
+
+// Severity ranks a validation issue
+type Severity int
+
+const (
+	SeverityInfo    Severity = iota // harmless, e.g. lowercase hex colors
+	SeverityWarning                 // readable, but likely to misbehave
+	SeverityError                   // breaks loading, playback or export
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityInfo:
+		return "info"
+	case SeverityWarning:
+		return "warning"
+	default:
+		return "error"
+	}
+}
+
+// Issue is a problem found by Check. Frame, Layer, Row and Col locate it
+// and are -1 when they do not apply; Layer -1 inside a frame means the
+// composited Cells.
+type Issue struct {
+	Severity Severity
+	Frame    int
+	Layer    int
+	Row      int
+	Col      int
+	Message  string
+	Fixed    string // what Repair did, empty when the issue was left alone
+}
+
+// Location describes where the issue is, e.g. "frame 3, layer 1, row 2, col 5"
+func (i Issue) Location() string {
+	var parts []string
+	if i.Frame >= 0 {
+		parts = append(parts, fmt.Sprintf("frame %d", i.Frame))
+	}
+	if i.Layer >= 0 {
+		parts = append(parts, fmt.Sprintf("layer %d", i.Layer))
+	}
+	if i.Row >= 0 {
+		parts = append(parts, fmt.Sprintf("row %d", i.Row))
+	}
+	if i.Col >= 0 {
+		parts = append(parts, fmt.Sprintf("col %d", i.Col))
+	}
+	if len(parts) == 0 {
+		return "file"
+	}
+	return strings.Join(parts, ", ")
+}
+
+func (i Issue) String() string {
+	s := fmt.Sprintf("%s: %s: %s", i.Severity, i.Location(), i.Message)
+	if i.Fixed != "" {
+		s += " (fixed: " + i.Fixed + ")"
+	}
+	return s
+}
+
+// Check validates the whole file, reporting every problem rather than
+// stopping at the first one like Validate
+func (a *AartFile) Check() []Issue {
+	c := checker{file: a}
+	c.run()
+	return c.issues
+}
+
+// Repair fixes what Check reports where it can: colors are normalized,
+// rows padded or cropped, indices renumbered and durations clamped. It
+// returns every issue found; repaired ones have Fixed set.
+func (a *AartFile) Repair() []Issue {
+	c := checker{file: a, fix: true}
+	c.run()
+	return c.issues
+}
+
+// HasErrors reports whether any issue is an unfixed error
+func HasErrors(issues []Issue) bool {
+	for _, i := range issues {
+		if i.Severity == SeverityError && i.Fixed == "" {
+			return true
+		}
+	}
+	return false
+}
+
+type checker struct {
+	file   *AartFile
+	fix    bool
+	issues []Issue
+}
+
+// report records an issue; when repairing, fix is applied and described
+func (c *checker) report(sev Severity, frame, layer, row, col int, msg string, fixed string, fix func()) {
+	issue := Issue{Severity: sev, Frame: frame, Layer: layer, Row: row, Col: col, Message: msg}
+	if c.fix && fix != nil {
+		fix()
+		issue.Fixed = fixed
+	}
+	c.issues = append(c.issues, issue)
+}
+
+func (c *checker) run() {
+	a := c.file
+	c.checkCanvas()
+	c.checkLayers()
+
+	if len(a.Frames) == 0 {
+		c.report(SeverityError, -1, -1, -1, -1, "file has no frames", "added a blank frame", func() {
+			a.AddFrame(blankGrid(a.Canvas.Width, a.Canvas.Height, blankCell), DefaultDuration)
+		})
+	}
+
+	for i := range a.Frames {
+		c.checkFrame(i)
+	}
+}
+
+func (c *checker) checkCanvas() {
+	a := c.file
+	if a.Canvas.Width > 0 && a.Canvas.Height > 0 {
+		return
+	}
+	msg := fmt.Sprintf("invalid canvas dimensions %dx%d", a.Canvas.Width, a.Canvas.Height)
+
+	// The first frame tells us the intended size
+	w, h := 0, 0
+	if len(a.Frames) > 0 {
+		h = len(a.Frames[0].Cells)
+		for _, row := range a.Frames[0].Cells {
+			w = max(w, len(row))
+		}
+	}
+	if w == 0 || h == 0 {
+		c.report(SeverityError, -1, -1, -1, -1, msg, "", nil)
+		return
+	}
+	c.report(SeverityError, -1, -1, -1, -1, msg, fmt.Sprintf("set to %dx%d from frame 0", w, h), func() {
+		a.Canvas = Canvas{Width: w, Height: h}
+	})
+}
+
+func (c *checker) checkLayers() {
+	for i := range c.file.Layers {
+		layer := &c.file.Layers[i]
+		if layer.Opacity < 0 || layer.Opacity > 1 {
+			opacity := min(max(layer.Opacity, 0), 1)
+			c.report(SeverityWarning, -1, i, -1, -1, fmt.Sprintf("opacity %g outside 0-1", layer.Opacity),
+				fmt.Sprintf("clamped to %g", opacity), func() { layer.Opacity = opacity })
+		}
+		if layer.BlendMode != "" && !ValidBlendMode(layer.BlendMode) {
+			c.report(SeverityWarning, -1, i, -1, -1, fmt.Sprintf("unknown blend mode %q", layer.BlendMode),
+				"set to "+BlendNormal, func() { layer.BlendMode = BlendNormal })
+		}
+	}
+}
+
+func (c *checker) checkFrame(i int) {
+	a := c.file
+	frame := &a.Frames[i]
+
+	if frame.Index != i {
+		c.report(SeverityWarning, i, -1, -1, -1, fmt.Sprintf("index %d is not sequential", frame.Index),
+			fmt.Sprintf("renumbered to %d", i), func() { frame.Index = i })
+	}
+
+	switch {
+	case frame.Duration <= 0:
+		c.report(SeverityWarning, i, -1, -1, -1, fmt.Sprintf("duration %dms", frame.Duration),
+			fmt.Sprintf("set to %dms", DefaultDuration), func() { frame.Duration = DefaultDuration })
+	case frame.Duration < MinDuration || frame.Duration > MaxDuration:
+		d := min(max(frame.Duration, MinDuration), MaxDuration)
+		c.report(SeverityWarning, i, -1, -1, -1, fmt.Sprintf("duration %dms outside %d-%dms", frame.Duration, MinDuration, MaxDuration),
+			fmt.Sprintf("clamped to %dms", d), func() { frame.Duration = d })
+	}
+
+	if n := len(frame.Layers); n > 0 && n != len(a.Layers) {
+		c.report(SeverityError, i, -1, -1, -1, fmt.Sprintf("has %d layers, expected %d", n, len(a.Layers)),
+			"padded or dropped layers to match", func() {
+				for len(frame.Layers) < len(a.Layers) {
+					frame.Layers = append(frame.Layers, FrameLayer{Cells: blankGrid(a.Canvas.Width, a.Canvas.Height, transparentCell)})
+				}
+				frame.Layers = frame.Layers[:len(a.Layers)]
+			})
+	}
+
+	frame.Cells = c.checkGrid(frame.Cells, i, -1, blankCell)
+	for l := range frame.Layers {
+		blank := transparentCell
+		if l == 0 {
+			blank = blankCell
+		}
+		frame.Layers[l].Cells = c.checkGrid(frame.Layers[l].Cells, i, l, blank)
+	}
+}
+
+// checkGrid checks a grid's shape and cells and returns it, repaired when
+// fixing. blank fills padded cells and replaces unreadable colors.
+func (c *checker) checkGrid(grid [][]Cell, frame, layer int, blank Cell) [][]Cell {
+	w, h := c.file.Canvas.Width, c.file.Canvas.Height
+	if w <= 0 || h <= 0 {
+		return grid
+	}
+
+	if len(grid) != h {
+		c.report(SeverityError, frame, layer, -1, -1, fmt.Sprintf("has %d rows, expected %d", len(grid), h),
+			"padded or cropped rows", func() {
+				for len(grid) < h {
+					grid = append(grid, blankGrid(w, 1, blank)[0])
+				}
+				grid = grid[:h]
+			})
+	}
+
+	for y := range grid {
+		if len(grid[y]) != w {
+			c.report(SeverityError, frame, layer, y, -1, fmt.Sprintf("has %d cells, expected %d", len(grid[y]), w),
+				"padded or cropped cells", func() {
+					for len(grid[y]) < w {
+						grid[y] = append(grid[y], blank)
+					}
+					grid[y] = grid[y][:w]
+				})
+		}
+		for x := range grid[y] {
+			c.checkCell(&grid[y][x], frame, layer, y, x, blank)
+		}
+	}
+	return grid
+}
+
+func (c *checker) checkCell(cell *Cell, frame, layer, row, col int, blank Cell) {
+	switch {
+	case cell.Char == "":
+		c.report(SeverityError, frame, layer, row, col, "empty char",
+			"set to space", func() { cell.Char = " " })
+	case !utf8.ValidString(cell.Char):
+		c.report(SeverityError, frame, layer, row, col, fmt.Sprintf("invalid UTF-8 char %q", cell.Char),
+			"set to space", func() { cell.Char = " " })
+	case uniseg.GraphemeClusterCount(cell.Char) > 1:
+		// A letter with combining marks or an emoji sequence is one
+		// grapheme and fine; only several of them overflow the cell
+		first, _, _, _ := uniseg.FirstGraphemeClusterInString(cell.Char, -1)
+		c.report(SeverityWarning, frame, layer, row, col, fmt.Sprintf("multi-grapheme char %q", cell.Char),
+			fmt.Sprintf("kept %q", first), func() { cell.Char = first })
+	}
+
+	c.checkColor(&cell.Foreground, "fg", blank.Foreground, frame, layer, row, col)
+	c.checkColor(&cell.Background, "bg", blank.Background, frame, layer, row, col)
+}
+
+// checkColor normalizes a color to "#RRGGBB". Empty colors are allowed;
+// they mean "inherit" on upper layers.
+func (c *checker) checkColor(color *string, name, fallback string, frame, layer, row, col int) {
+	if *color == "" {
+		return
+	}
+	r, g, b, ok := ParseHexColor(*color)
+	if !ok {
+		c.report(SeverityError, frame, layer, row, col, fmt.Sprintf("invalid %s color %q", name, *color),
+			fmt.Sprintf("set to %q", fallback), func() { *color = fallback })
+		return
+	}
+
+	canonical := FormatHexColor(r, g, b)
+	if *color == canonical {
+		return
+	}
+	sev := SeverityWarning
+	if strings.EqualFold(*color, canonical) {
+		sev = SeverityInfo
+	}
+	c.report(sev, frame, layer, row, col, fmt.Sprintf("non-canonical %s color %q", name, *color),
+		"normalized to "+canonical, func() { *color = canonical })
+}
+
+// blankGrid returns a width x height grid filled with cell
+func blankGrid(width, height int, cell Cell) [][]Cell {
+	grid := make([][]Cell, height)
+	for y := range grid {
+		grid[y] = make([]Cell, width)
+		for x := range grid[y] {
+			grid[y][x] = cell
+		}
+	}
+	return grid
+}
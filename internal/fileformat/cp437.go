@@ -0,0 +1,91 @@
+package fileformat
+
+// cp437 maps IBM code page 437 bytes to Unicode. ANSI art shows the
+// control range as glyphs too, except where the byte acts as a control.
+var cp437 = [256]rune{
+	' ', '☺', '☻', '♥', '♦', '♣', '♠', '•', '◘', '○', '◙', '♂', '♀', '♪', '♫', '☼',
+	'►', '◄', '↕', '‼', '¶', '§', '▬', '↨', '↑', '↓', '→', '←', '∟', '↔', '▲', '▼',
+	' ', '!', '"', '#', '$', '%', '&', '\'', '(', ')', '*', '+', ',', '-', '.', '/',
+	'0', '1', '2', '3', '4', '5', '6', '7', '8', '9', ':', ';', '<', '=', '>', '?',
+	'@', 'A', 'B', 'C', 'D', 'E', 'F', 'G', 'H', 'I', 'J', 'K', 'L', 'M', 'N', 'O',
+	'P', 'Q', 'R', 'S', 'T', 'U', 'V', 'W', 'X', 'Y', 'Z', '[', '\\', ']', '^', '_',
+	'`', 'a', 'b', 'c', 'd', 'e', 'f', 'g', 'h', 'i', 'j', 'k', 'l', 'm', 'n', 'o',
+	'p', 'q', 'r', 's', 't', 'u', 'v', 'w', 'x', 'y', 'z', '{', '|', '}', '~', '⌂',
+	'Ç', 'ü', 'é', 'â', 'ä', 'à', 'å', 'ç', 'ê', 'ë', 'è', 'ï', 'î', 'ì', 'Ä', 'Å',
+	'É', 'æ', 'Æ', 'ô', 'ö', 'ò', 'û', 'ù', 'ÿ', 'Ö', 'Ü', '¢', '£', '¥', '₧', 'ƒ',
+	'á', 'í', 'ó', 'ú', 'ñ', 'Ñ', 'ª', 'º', '¿', '⌐', '¬', '½', '¼', '¡', '«', '»',
+	'░', '▒', '▓', '│', '┤', '╡', '╢', '╖', '╕', '╣', '║', '╗', '╝', '╜', '╛', '┐',
+	'└', '┴', '┬', '├', '─', '┼', '╞', '╟', '╚', '╔', '╩', '╦', '╠', '═', '╬', '╧',
+	'╨', '╤', '╥', '╙', '╘', '╒', '╓', '╫', '╪', '┘', '┌', '█', '▄', '▌', '▐', '▀',
+	'α', 'ß', 'Γ', 'π', 'Σ', 'σ', 'µ', 'τ', 'Φ', 'Θ', 'Ω', 'δ', '∞', 'φ', 'ε', '∩',
+	'≡', '±', '≥', '≤', '⌠', '⌡', '÷', '≈', '°', '∙', '·', '√', 'ⁿ', '²', '■', ' ',
+}
+
+// cp437Controls are the bytes that ANSI art viewers treat as controls
+// rather than glyphs, so they are never written as characters
+var cp437Controls = map[byte]bool{
+	0x00: true, 0x07: true, 0x08: true, 0x09: true, 0x0A: true,
+	0x0D: true, 0x1A: true, 0x1B: true,
+}
+
+// cp437Lookalikes stand in for characters CP437 does not have
+var cp437Lookalikes = map[rune]rune{
+	'▘': '▀', '▝': '▀', '▖': '▄', '▗': '▄',
+	'▚': '▒', '▞': '▒', '▙': '█', '▛': '█', '▜': '█', '▟': '█',
+	'▁': '▄', '▂': '▄', '▃': '▄', '▅': '▄', '▆': '█', '▇': '█',
+	'▉': '█', '▊': '█', '▋': '▌', '▍': '▌', '▎': '▌', '▏': '│',
+	'▔': '▀', '▕': '│',
+	'━': '─', '┃': '│', '┏': '┌', '┓': '┐', '┗': '└', '┛': '┘',
+	'┣': '├', '┫': '┤', '┳': '┬', '┻': '┴', '╋': '┼',
+	'╭': '┌', '╮': '┐', '╯': '┘', '╰': '└',
+	'‘': '\'', '’': '\'', '“': '"', '”': '"', '–': '-', '—': '-', '…': '.',
+	'●': '•', '◆': '♦', '★': '*', '✓': '√', '×': 'x',
+}
+
+var cp437Bytes = func() map[rune]byte {
+	m := make(map[rune]byte, 256)
+	for b, r := range cp437 {
+		if _, ok := m[r]; !ok && !cp437Controls[byte(b)] {
+			m[r] = byte(b)
+		}
+	}
+	return m
+}()
+
+// toCP437 returns the CP437 byte for a cell character: the exact glyph, a
+// look-alike, a braille pattern as a shade by its dot count, or '?'
+func toCP437(char string) byte {
+	r := ' '
+	for _, c := range char {
+		r = c
+		break
+	}
+	if b, ok := cp437Bytes[r]; ok {
+		return b
+	}
+	if l, ok := cp437Lookalikes[r]; ok {
+		return cp437Bytes[l]
+	}
+	if r >= 0x2800 && r <= 0x28FF {
+		dots := 0
+		for bits := r - 0x2800; bits > 0; bits &= bits - 1 {
+			dots++
+		}
+		return [9]byte{' ', 0xB0, 0xB0, 0xB0, 0xB1, 0xB1, 0xB2, 0xB2, 0xDB}[dots]
+	}
+	return '?'
+}
+
+// decodeCP437 converts CP437 text to UTF-8, keeping the bytes viewers
+// treat as controls
+func decodeCP437(data []byte) []byte {
+	out := make([]byte, 0, len(data)*2)
+	for _, b := range data {
+		if b < 0x80 && (b >= 0x20 && b != 0x7F || cp437Controls[b]) {
+			out = append(out, b)
+			continue
+		}
+		out = append(out, string(cp437[b])...)
+	}
+	return out
+}
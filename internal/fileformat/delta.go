@@ -0,0 +1,143 @@
+package fileformat
+
+import "fmt"
+
+// Frame encodings
+const (
+	EncodingFull  = ""      // the frame stores every cell (keyframe)
+	EncodingDelta = "delta" // the frame stores the cells changed since the previous frame
+)
+
+// CellDelta is a changed cell of a delta frame
+type CellDelta struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+	Cell
+}
+
+// IsDelta reports whether the frame is stored as changes to the previous one
+func (f *Frame) IsDelta() bool {
+	return f.Encoding == EncodingDelta
+}
+
+// DiffGrid lists the cells of cur that differ from prev. It returns false
+// when the grids do not have the same shape.
+func DiffGrid(prev, cur [][]Cell) ([]CellDelta, bool) {
+	if len(prev) != len(cur) {
+		return nil, false
+	}
+	var delta []CellDelta
+	for y := range cur {
+		if len(prev[y]) != len(cur[y]) {
+			return nil, false
+		}
+		for x, c := range cur[y] {
+			if prev[y][x] != c {
+				delta = append(delta, CellDelta{X: x, Y: y, Cell: c})
+			}
+		}
+	}
+	return delta, true
+}
+
+// ApplyDelta returns a copy of prev with the delta applied
+func ApplyDelta(prev [][]Cell, delta []CellDelta) ([][]Cell, error) {
+	out := make([][]Cell, len(prev))
+	for y := range prev {
+		out[y] = append([]Cell(nil), prev[y]...)
+	}
+	for _, d := range delta {
+		if d.Y < 0 || d.Y >= len(out) || d.X < 0 || d.X >= len(out[d.Y]) {
+			return nil, fmt.Errorf("delta cell %d,%d out of bounds", d.X, d.Y)
+		}
+		out[d.Y][d.X] = d.Cell
+	}
+	return out, nil
+}
+
+// EncodeDeltas returns a copy of the file where every frame that is not a
+// keyframe stores only its changes. A keyframe is written every interval
+// frames, and whenever a delta would not be smaller than the full frame.
+// The receiver is left untouched.
+func (a *AartFile) EncodeDeltas(interval int) *AartFile {
+	out := *a
+	out.Frames = make([]Frame, len(a.Frames))
+	copy(out.Frames, a.Frames)
+	if interval <= 0 {
+		return &out
+	}
+
+	for i := 1; i < len(a.Frames); i++ {
+		if i%interval == 0 {
+			continue
+		}
+		prev, cur := &a.Frames[i-1], &a.Frames[i]
+		if len(prev.Layers) != len(cur.Layers) {
+			continue
+		}
+
+		delta, ok := DiffGrid(prev.Cells, cur.Cells)
+		changed, total := len(delta), a.Canvas.Width*a.Canvas.Height
+		layers := make([]FrameLayer, len(cur.Layers))
+		for l := range cur.Layers {
+			d, same := DiffGrid(prev.Layers[l].Cells, cur.Layers[l].Cells)
+			ok = ok && same
+			layers[l] = FrameLayer{Delta: d}
+			changed += len(d)
+			total += a.Canvas.Width * a.Canvas.Height
+		}
+		if !ok || changed*2 >= total {
+			continue
+		}
+
+		f := *cur
+		f.Encoding = EncodingDelta
+		f.Cells = nil
+		f.Delta = delta
+		if len(layers) > 0 {
+			f.Layers = layers
+		}
+		out.Frames[i] = f
+	}
+	return &out
+}
+
+// DecodeDeltas expands delta frames into full frames in place
+func (a *AartFile) DecodeDeltas() error {
+	for i := range a.Frames {
+		f := &a.Frames[i]
+		if !f.IsDelta() {
+			continue
+		}
+		if i == 0 {
+			return fmt.Errorf("frame 0: delta frame without a previous frame")
+		}
+		if err := f.applyTo(&a.Frames[i-1]); err != nil {
+			return fmt.Errorf("frame %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// applyTo turns a delta frame into a full frame using the decoded previous
+// frame
+func (f *Frame) applyTo(prev *Frame) error {
+	cells, err := ApplyDelta(prev.Cells, f.Delta)
+	if err != nil {
+		return err
+	}
+	if len(f.Layers) != len(prev.Layers) {
+		return fmt.Errorf("has %d layers, previous frame has %d", len(f.Layers), len(prev.Layers))
+	}
+	for l := range f.Layers {
+		grid, err := ApplyDelta(prev.Layers[l].Cells, f.Layers[l].Delta)
+		if err != nil {
+			return fmt.Errorf("layer %d: %w", l, err)
+		}
+		f.Layers[l] = FrameLayer{Cells: grid}
+	}
+	f.Cells = cells
+	f.Delta = nil
+	f.Encoding = EncodingFull
+	return nil
+}
@@ -19,15 +19,40 @@ const (
 	FormatHTML ExportFormat = "html"
 	FormatSVG  ExportFormat = "svg"
 	FormatGIF  ExportFormat = "gif"
+	FormatPNG  ExportFormat = "png"
+
+	// FormatSpriteSheet writes all frames in a grid to one PNG, with a
+	// JSON atlas next to it
+	FormatSpriteSheet ExportFormat = "spritesheet"
+
+	// FormatAsciicast writes an asciinema v2 recording (.cast)
+	FormatAsciicast ExportFormat = "asciicast"
+
+	// FormatBBS writes DOS ANSI art (.ans): CP437, iCE colors and SAUCE
+	FormatBBS ExportFormat = "bbs"
+
+	// FormatGo writes Go source declaring the animation and a player
+	FormatGo ExportFormat = "go"
+
+	// FormatFrames writes all frames as plain text between delimiter lines
+	FormatFrames ExportFormat = "frames"
 )
 
 // ExportOptions contains export configuration
 type ExportOptions struct {
 	Format      ExportFormat
-	FrameIndex  int  // -1 for all frames
+	FrameIndex  int // -1 for all frames
 	IncludeMeta bool
-	Compact     bool // For JSON
-	Colors      bool // For ANSI/TXT
+	Compact     bool      // For JSON
+	Colors      bool      // For ANSI/TXT
+	ColorMode   ColorMode // For ANSI/asciicast, how colors are written ("" = truecolor)
+	CellWidth   int       // For GIF/PNG/SVG, pixels per cell (0 = default)
+	CellHeight  int       // For GIF/PNG/SVG, pixels per cell (0 = default)
+	Columns     int       // For sprite sheets, frames per row (0 = square grid)
+	GoPackage   string    // For Go, the package name ("" = main)
+	GoVar       string    // For Go, the variable name ("" = from the file name)
+	Delimiter   string    // For frames, the line between frames ("" = DefaultFrameDelimiter)
+	Durations   bool      // For frames, start every frame with a duration header
 }
 
 // Export exports to the specified format
@@ -45,6 +70,20 @@ func Export(aart *AartFile, path string, opts ExportOptions) error {
 		return exportHTML(aart, path, opts)
 	case FormatSVG:
 		return exportSVG(aart, path, opts)
+	case FormatGIF:
+		return exportGIF(aart, path, opts)
+	case FormatPNG:
+		return exportPNG(aart, path, opts)
+	case FormatSpriteSheet:
+		return exportSpriteSheet(aart, path, opts)
+	case FormatAsciicast:
+		return exportAsciicast(aart, path, opts)
+	case FormatBBS:
+		return exportBBS(aart, path, opts)
+	case FormatGo:
+		return exportGo(aart, path, opts)
+	case FormatFrames:
+		return exportTextFrames(aart, path, opts)
 	default:
 		return fmt.Errorf("unsupported export format: %s", opts.Format)
 	}
@@ -116,45 +155,6 @@ func exportCSV(aart *AartFile, path string, opts ExportOptions) error {
 	return nil
 }
 
-// exportANSI exports to ANSI art format
-func exportANSI(aart *AartFile, path string, opts ExportOptions) error {
-	file, err := os.Create(path)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-
-	// Export single frame or first frame
-	frameIdx := 0
-	if opts.FrameIndex >= 0 && opts.FrameIndex < len(aart.Frames) {
-		frameIdx = opts.FrameIndex
-	}
-
-	frame := aart.Frames[frameIdx]
-
-	var output strings.Builder
-
-	for _, row := range frame.Cells {
-		for _, cell := range row {
-			if opts.Colors {
-				// ANSI color codes
-				fg := hexToANSI(cell.Foreground)
-				bg := hexToANSI(cell.Background)
-				output.WriteString(fmt.Sprintf("\x1b[38;5;%dm\x1b[48;5;%dm%s", fg, bg, cell.Char))
-			} else {
-				output.WriteString(cell.Char)
-			}
-		}
-		if opts.Colors {
-			output.WriteString("\x1b[0m") // Reset
-		}
-		output.WriteString("\n")
-	}
-
-	_, err = file.WriteString(output.String())
-	return err
-}
-
 // exportTXT exports to plain text
 func exportTXT(aart *AartFile, path string, opts ExportOptions) error {
 	file, err := os.Create(path)
@@ -192,120 +192,8 @@ func exportTXT(aart *AartFile, path string, opts ExportOptions) error {
 	return err
 }
 
-// exportHTML exports to HTML format
-func exportHTML(aart *AartFile, path string, opts ExportOptions) error {
-	file, err := os.Create(path)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-
-	frameIdx := 0
-	if opts.FrameIndex >= 0 && opts.FrameIndex < len(aart.Frames) {
-		frameIdx = opts.FrameIndex
-	}
-
-	frame := aart.Frames[frameIdx]
-
-	var html strings.Builder
-	
-	html.WriteString("<!DOCTYPE html>\n<html>\n<head>\n")
-	html.WriteString(fmt.Sprintf("<title>%s</title>\n", aart.Metadata.Title))
-	html.WriteString("<style>\n")
-	html.WriteString("body { background: #000; margin: 20px; }\n")
-	html.WriteString("pre { font-family: monospace; line-height: 1; margin: 0; }\n")
-	html.WriteString(".cell { display: inline-block; }\n")
-	html.WriteString("</style>\n")
-	html.WriteString("</head>\n<body>\n<pre>\n")
-
-	for _, row := range frame.Cells {
-		for _, cell := range row {
-			if opts.Colors {
-				html.WriteString(fmt.Sprintf(
-					"<span class=\"cell\" style=\"color:%s;background:%s\">%s</span>",
-					cell.Foreground,
-					cell.Background,
-					escapeHTML(cell.Char),
-				))
-			} else {
-				html.WriteString(escapeHTML(cell.Char))
-			}
-		}
-		html.WriteString("\n")
-	}
-
-	html.WriteString("</pre>\n</body>\n</html>")
-
-	_, err = file.WriteString(html.String())
-	return err
-}
-
-// exportSVG exports to SVG format
-func exportSVG(aart *AartFile, path string, opts ExportOptions) error {
-	file, err := os.Create(path)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-
-	frameIdx := 0
-	if opts.FrameIndex >= 0 && opts.FrameIndex < len(aart.Frames) {
-		frameIdx = opts.FrameIndex
-	}
-
-	frame := aart.Frames[frameIdx]
-
-	cellWidth := 10
-	cellHeight := 16
-	width := aart.Canvas.Width * cellWidth
-	height := aart.Canvas.Height * cellHeight
-
-	var svg strings.Builder
-	svg.WriteString(fmt.Sprintf("<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%d\" height=\"%d\">\n", width, height))
-	svg.WriteString("<style>text { font-family: monospace; font-size: 14px; }</style>\n")
-
-	for y, row := range frame.Cells {
-		for x, cell := range row {
-			px := x * cellWidth
-			py := (y + 1) * cellHeight
-			
-			// Background rect
-			if cell.Background != "#000000" && cell.Background != "" {
-				svg.WriteString(fmt.Sprintf(
-					"<rect x=\"%d\" y=\"%d\" width=\"%d\" height=\"%d\" fill=\"%s\"/>\n",
-					px, py-cellHeight, cellWidth, cellHeight, cell.Background,
-				))
-			}
-			
-			// Text
-			svg.WriteString(fmt.Sprintf(
-				"<text x=\"%d\" y=\"%d\" fill=\"%s\">%s</text>\n",
-				px, py, cell.Foreground, escapeHTML(cell.Char),
-			))
-		}
-	}
-
-	svg.WriteString("</svg>")
-
-	_, err = file.WriteString(svg.String())
-	return err
-}
-
 // Helper functions
 
-func hexToANSI(hex string) int {
-	// Simplified hex to ANSI 256 color conversion
-	// For a full implementation, parse hex and map to closest ANSI color
-	if hex == "#FFFFFF" || hex == "#ffffff" {
-		return 15 // White
-	}
-	if hex == "#000000" || hex == "#000000" {
-		return 0 // Black
-	}
-	// Default to white
-	return 15
-}
-
 func escapeHTML(s string) string {
 	s = strings.ReplaceAll(s, "&", "&amp;")
 	s = strings.ReplaceAll(s, "<", "&lt;")
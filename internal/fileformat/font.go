@@ -0,0 +1,192 @@
+package fileformat
+
+// Built-in bitmap font used to rasterize cells for image exports, so the
+// output does not depend on fonts installed on the system.
+//
+// ASCII glyphs are drawn on a 5x8 grid: rows 0-6 hold capitals and digits,
+// row 7 holds descenders. Block elements, shades, box drawing and braille
+// are generated from their geometry at the target cell size instead.
+
+const (
+	glyphWidth  = 5
+	glyphHeight = 8
+)
+
+// asciiGlyphs holds the printable ASCII range, ' ' through '~'
+var asciiGlyphs = [95][glyphHeight]string{
+	{".....", ".....", ".....", ".....", ".....", ".....", ".....", "....."}, // ' '
+	{"..#..", "..#..", "..#..", "..#..", "..#..", ".....", "..#..", "....."}, // !
+	{".#.#.", ".#.#.", ".....", ".....", ".....", ".....", ".....", "....."}, // "
+	{".#.#.", ".#.#.", "#####", ".#.#.", "#####", ".#.#.", ".#.#.", "....."}, // #
+	{"..#..", ".####", "#.#..", ".###.", "..#.#", "####.", "..#..", "....."}, // $
+	{"##...", "##..#", "...#.", "..#..", ".#...", "#..##", "...##", "....."}, // %
+	{".##..", "#..#.", "#.#..", ".#...", "#.#.#", "#..#.", ".##.#", "....."}, // &
+	{"..#..", "..#..", ".....", ".....", ".....", ".....", ".....", "....."}, // '
+	{"...#.", "..#..", ".#...", ".#...", ".#...", "..#..", "...#.", "....."}, // (
+	{".#...", "..#..", "...#.", "...#.", "...#.", "..#..", ".#...", "....."}, // )
+	{".....", "..#..", "#.#.#", ".###.", "#.#.#", "..#..", ".....", "....."}, // *
+	{".....", "..#..", "..#..", "#####", "..#..", "..#..", ".....", "....."}, // +
+	{".....", ".....", ".....", ".....", ".....", ".##..", "..#..", ".#..."}, // ,
+	{".....", ".....", ".....", "#####", ".....", ".....", ".....", "....."}, // -
+	{".....", ".....", ".....", ".....", ".....", ".##..", ".##..", "....."}, // .
+	{".....", "....#", "...#.", "..#..", ".#...", "#....", ".....", "....."}, // /
+	{".###.", "#...#", "#..##", "#.#.#", "##..#", "#...#", ".###.", "....."}, // 0
+	{"..#..", ".##..", "..#..", "..#..", "..#..", "..#..", ".###.", "....."}, // 1
+	{".###.", "#...#", "....#", "...#.", "..#..", ".#...", "#####", "....."}, // 2
+	{"#####", "...#.", "..#..", "...#.", "....#", "#...#", ".###.", "....."}, // 3
+	{"...#.", "..##.", ".#.#.", "#..#.", "#####", "...#.", "...#.", "....."}, // 4
+	{"#####", "#....", "####.", "....#", "....#", "#...#", ".###.", "....."}, // 5
+	{"..##.", ".#...", "#....", "####.", "#...#", "#...#", ".###.", "....."}, // 6
+	{"#####", "....#", "...#.", "..#..", ".#...", ".#...", ".#...", "....."}, // 7
+	{".###.", "#...#", "#...#", ".###.", "#...#", "#...#", ".###.", "....."}, // 8
+	{".###.", "#...#", "#...#", ".####", "....#", "...#.", ".##..", "....."}, // 9
+	{".....", ".##..", ".##..", ".....", ".##..", ".##..", ".....", "....."}, // :
+	{".....", ".##..", ".##..", ".....", ".##..", "..#..", ".#...", "....."}, // ;
+	{"...#.", "..#..", ".#...", "#....", ".#...", "..#..", "...#.", "....."}, // <
+	{".....", ".....", "#####", ".....", "#####", ".....", ".....", "....."}, // =
+	{".#...", "..#..", "...#.", "....#", "...#.", "..#..", ".#...", "....."}, // >
+	{".###.", "#...#", "....#", "...#.", "..#..", ".....", "..#..", "....."}, // ?
+	{".###.", "#...#", "....#", ".##.#", "#.#.#", "#.#.#", ".###.", "....."}, // @
+	{".###.", "#...#", "#...#", "#####", "#...#", "#...#", "#...#", "....."}, // A
+	{"####.", "#...#", "#...#", "####.", "#...#", "#...#", "####.", "....."}, // B
+	{".###.", "#...#", "#....", "#....", "#....", "#...#", ".###.", "....."}, // C
+	{"###..", "#..#.", "#...#", "#...#", "#...#", "#..#.", "###..", "....."}, // D
+	{"#####", "#....", "#....", "####.", "#....", "#....", "#####", "....."}, // E
+	{"#####", "#....", "#....", "####.", "#....", "#....", "#....", "....."}, // F
+	{".###.", "#...#", "#....", "#.###", "#...#", "#...#", ".####", "....."}, // G
+	{"#...#", "#...#", "#...#", "#####", "#...#", "#...#", "#...#", "....."}, // H
+	{".###.", "..#..", "..#..", "..#..", "..#..", "..#..", ".###.", "....."}, // I
+	{"..###", "...#.", "...#.", "...#.", "...#.", "#..#.", ".##..", "....."}, // J
+	{"#...#", "#..#.", "#.#..", "##...", "#.#..", "#..#.", "#...#", "....."}, // K
+	{"#....", "#....", "#....", "#....", "#....", "#....", "#####", "....."}, // L
+	{"#...#", "##.##", "#.#.#", "#.#.#", "#...#", "#...#", "#...#", "....."}, // M
+	{"#...#", "#...#", "##..#", "#.#.#", "#..##", "#...#", "#...#", "....."}, // N
+	{".###.", "#...#", "#...#", "#...#", "#...#", "#...#", ".###.", "....."}, // O
+	{"####.", "#...#", "#...#", "####.", "#....", "#....", "#....", "....."}, // P
+	{".###.", "#...#", "#...#", "#...#", "#.#.#", "#..#.", ".##.#", "....."}, // Q
+	{"####.", "#...#", "#...#", "####.", "#.#..", "#..#.", "#...#", "....."}, // R
+	{".####", "#....", "#....", ".###.", "....#", "....#", "####.", "....."}, // S
+	{"#####", "..#..", "..#..", "..#..", "..#..", "..#..", "..#..", "....."}, // T
+	{"#...#", "#...#", "#...#", "#...#", "#...#", "#...#", ".###.", "....."}, // U
+	{"#...#", "#...#", "#...#", "#...#", "#...#", ".#.#.", "..#..", "....."}, // V
+	{"#...#", "#...#", "#...#", "#.#.#", "#.#.#", "#.#.#", ".#.#.", "....."}, // W
+	{"#...#", "#...#", ".#.#.", "..#..", ".#.#.", "#...#", "#...#", "....."}, // X
+	{"#...#", "#...#", ".#.#.", "..#..", "..#..", "..#..", "..#..", "....."}, // Y
+	{"#####", "....#", "...#.", "..#..", ".#...", "#....", "#####", "....."}, // Z
+	{".###.", ".#...", ".#...", ".#...", ".#...", ".#...", ".###.", "....."}, // [
+	{".....", "#....", ".#...", "..#..", "...#.", "....#", ".....", "....."}, // \
+	{".###.", "...#.", "...#.", "...#.", "...#.", "...#.", ".###.", "....."}, // ]
+	{"..#..", ".#.#.", "#...#", ".....", ".....", ".....", ".....", "....."}, // ^
+	{".....", ".....", ".....", ".....", ".....", ".....", ".....", "#####"}, // _
+	{".#...", "..#..", ".....", ".....", ".....", ".....", ".....", "....."}, // `
+	{".....", ".....", ".###.", "....#", ".####", "#...#", ".####", "....."}, // a
+	{"#....", "#....", "#.##.", "##..#", "#...#", "#...#", "####.", "....."}, // b
+	{".....", ".....", ".###.", "#....", "#....", "#...#", ".###.", "....."}, // c
+	{"....#", "....#", ".##.#", "#..##", "#...#", "#...#", ".####", "....."}, // d
+	{".....", ".....", ".###.", "#...#", "#####", "#....", ".###.", "....."}, // e
+	{"..##.", ".#..#", ".#...", "###..", ".#...", ".#...", ".#...", "....."}, // f
+	{".....", ".....", ".####", "#...#", "#...#", ".####", "....#", ".###."}, // g
+	{"#....", "#....", "#.##.", "##..#", "#...#", "#...#", "#...#", "....."}, // h
+	{"..#..", ".....", ".##..", "..#..", "..#..", "..#..", ".###.", "....."}, // i
+	{"...#.", ".....", "..##.", "...#.", "...#.", "...#.", "#..#.", ".##.."}, // j
+	{"#....", "#....", "#..#.", "#.#..", "##...", "#.#..", "#..#.", "....."}, // k
+	{".##..", "..#..", "..#..", "..#..", "..#..", "..#..", ".###.", "....."}, // l
+	{".....", ".....", "##.#.", "#.#.#", "#.#.#", "#...#", "#...#", "....."}, // m
+	{".....", ".....", "#.##.", "##..#", "#...#", "#...#", "#...#", "....."}, // n
+	{".....", ".....", ".###.", "#...#", "#...#", "#...#", ".###.", "....."}, // o
+	{".....", ".....", "####.", "#...#", "#...#", "####.", "#....", "#...."}, // p
+	{".....", ".....", ".####", "#...#", "#...#", ".####", "....#", "....#"}, // q
+	{".....", ".....", "#.##.", "##..#", "#....", "#....", "#....", "....."}, // r
+	{".....", ".....", ".###.", "#....", ".###.", "....#", "####.", "....."}, // s
+	{".#...", ".#...", "###..", ".#...", ".#...", ".#..#", "..##.", "....."}, // t
+	{".....", ".....", "#...#", "#...#", "#...#", "#..##", ".##.#", "....."}, // u
+	{".....", ".....", "#...#", "#...#", "#...#", ".#.#.", "..#..", "....."}, // v
+	{".....", ".....", "#...#", "#...#", "#.#.#", "#.#.#", ".#.#.", "....."}, // w
+	{".....", ".....", "#...#", ".#.#.", "..#..", ".#.#.", "#...#", "....."}, // x
+	{".....", ".....", "#...#", "#...#", "#...#", ".####", "....#", ".###."}, // y
+	{".....", ".....", "#####", "...#.", "..#..", ".#...", "#####", "....."}, // z
+	{"...#.", "..#..", "..#..", ".#...", "..#..", "..#..", "...#.", "....."}, // {
+	{"..#..", "..#..", "..#..", "..#..", "..#..", "..#..", "..#..", "....."}, // |
+	{".#...", "..#..", "..#..", "...#.", "..#..", "..#..", ".#...", "....."}, // }
+	{".....", ".....", ".#...", "#.#.#", "...#.", ".....", ".....", "....."}, // ~
+}
+
+// extraGlyphs covers the non-ASCII characters the converter and editor
+// produce that have no geometric rendering
+var extraGlyphs = map[rune][glyphHeight]string{
+	'·': {".....", ".....", ".....", "..#..", ".....", ".....", ".....", "....."},
+	'•': {".....", ".....", ".###.", ".###.", ".###.", ".....", ".....", "....."},
+	'°': {".##..", "#..#.", ".##..", ".....", ".....", ".....", ".....", "....."},
+	'×': {".....", "#...#", ".#.#.", "..#..", ".#.#.", "#...#", ".....", "....."},
+	'÷': {".....", "..#..", ".....", "#####", ".....", "..#..", ".....", "....."},
+	'←': {".....", "..#..", ".#...", "#####", ".#...", "..#..", ".....", "....."},
+	'→': {".....", "..#..", "...#.", "#####", "...#.", "..#..", ".....", "....."},
+	'↑': {"..#..", ".###.", "#.#.#", "..#..", "..#..", "..#..", ".....", "....."},
+	'↓': {"..#..", "..#..", "..#..", "#.#.#", ".###.", "..#..", ".....", "....."},
+	'●': {".....", ".###.", "#####", "#####", "#####", ".###.", ".....", "....."},
+	'○': {".....", ".###.", "#...#", "#...#", "#...#", ".###.", ".....", "....."},
+	'◆': {"..#..", ".###.", "#####", ".###.", "..#..", ".....", ".....", "....."},
+	'◇': {"..#..", ".#.#.", "#...#", ".#.#.", "..#..", ".....", ".....", "....."},
+	'■': {".....", "#####", "#####", "#####", "#####", "#####", ".....", "....."},
+	'□': {".....", "#####", "#...#", "#...#", "#...#", "#####", ".....", "....."},
+	'▲': {".....", "..#..", ".###.", ".###.", "#####", "#####", ".....", "....."},
+	'▼': {".....", "#####", "#####", ".###.", ".###.", "..#..", ".....", "....."},
+	'◀': {"....#", "...##", ".####", "#####", ".####", "...##", "....#", "....."},
+	'▶': {"#....", "##...", "####.", "#####", "####.", "##...", "#....", "....."},
+	'♥': {".....", ".#.#.", "#####", "#####", ".###.", "..#..", ".....", "....."},
+	'★': {"..#..", "..#..", "#####", ".###.", ".#.#.", "#...#", ".....", "....."},
+	'☆': {"..#..", ".#.#.", "##.##", "#...#", ".#.#.", "#.#.#", ".....", "....."},
+	'…': {".....", ".....", ".....", ".....", ".....", ".....", "#.#.#", "....."},
+}
+
+// glyphBitmap is a compiled glyph: bit x of row y is set when the pixel
+// at column x is lit
+type glyphBitmap [glyphHeight]uint8
+
+var compiledGlyphs = compileGlyphs()
+
+func compileGlyphs() map[rune]glyphBitmap {
+	out := make(map[rune]glyphBitmap, len(asciiGlyphs)+len(extraGlyphs))
+	compile := func(r rune, rows [glyphHeight]string) {
+		var g glyphBitmap
+		for y, row := range rows {
+			for x := 0; x < len(row) && x < glyphWidth; x++ {
+				if row[x] == '#' {
+					g[y] |= 1 << x
+				}
+			}
+		}
+		out[r] = g
+	}
+	for i, rows := range asciiGlyphs {
+		compile(rune(' '+i), rows)
+	}
+	for r, rows := range extraGlyphs {
+		compile(r, rows)
+	}
+	return out
+}
+
+// Box drawing arm weights
+const (
+	armNone = iota
+	armLight
+	armHeavy
+	armDouble
+)
+
+// boxGlyphs gives the arms (up, right, down, left) of box drawing
+// characters
+var boxGlyphs = map[rune][4]uint8{
+	'─': {0, 1, 0, 1}, '━': {0, 2, 0, 2}, '│': {1, 0, 1, 0}, '┃': {2, 0, 2, 0},
+	'┌': {0, 1, 1, 0}, '┐': {0, 0, 1, 1}, '└': {1, 1, 0, 0}, '┘': {1, 0, 0, 1},
+	'┏': {0, 2, 2, 0}, '┓': {0, 0, 2, 2}, '┗': {2, 2, 0, 0}, '┛': {2, 0, 0, 2},
+	'├': {1, 1, 1, 0}, '┤': {1, 0, 1, 1}, '┬': {0, 1, 1, 1}, '┴': {1, 1, 0, 1},
+	'┼': {1, 1, 1, 1}, '┣': {2, 2, 2, 0}, '┫': {2, 0, 2, 2}, '┳': {0, 2, 2, 2},
+	'┻': {2, 2, 0, 2}, '╋': {2, 2, 2, 2},
+	'═': {0, 3, 0, 3}, '║': {3, 0, 3, 0}, '╔': {0, 3, 3, 0}, '╗': {0, 0, 3, 3},
+	'╚': {3, 3, 0, 0}, '╝': {3, 0, 0, 3}, '╠': {3, 3, 3, 0}, '╣': {3, 0, 3, 3},
+	'╦': {0, 3, 3, 3}, '╩': {3, 3, 0, 3}, '╬': {3, 3, 3, 3},
+	'╭': {0, 1, 1, 0}, '╮': {0, 0, 1, 1}, '╰': {1, 1, 0, 0}, '╯': {1, 0, 0, 1},
+	'╴': {0, 0, 0, 1}, '╵': {1, 0, 0, 0}, '╶': {0, 1, 0, 0}, '╷': {0, 0, 1, 0},
+}
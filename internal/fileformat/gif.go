@@ -0,0 +1,283 @@
+package fileformat
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"os"
+	"sort"
+)
+
+// maxGIFColors leaves one of the 256 palette entries for transparency,
+// which frame deltas use for unchanged pixels
+const maxGIFColors = 255
+
+// exportGIF rasterizes the frames with the built-in font and writes an
+// animated GIF. Every frame after the first only stores the rectangle that
+// changed, with unchanged pixels transparent.
+func exportGIF(aart *AartFile, path string, opts ExportOptions) error {
+	start, end := frameRange(aart, opts)
+	if start >= end {
+		return fmt.Errorf("no frames to export")
+	}
+
+	r := NewRasterizer(opts.CellWidth, opts.CellHeight)
+	r.Monochrome = !opts.Colors
+	width, height := r.Size(aart.Canvas.Width, aart.Canvas.Height)
+
+	palette := gifPalette(aart.Frames[start:end], r)
+	transparent := uint8(len(palette))
+	palette = append(palette, color.RGBA{})
+	lookup := newPaletteLookup(palette[:transparent])
+
+	out := &gif.GIF{
+		Config: image.Config{ColorModel: palette, Width: width, Height: height},
+	}
+
+	var prev []uint8
+	for i := start; i < end; i++ {
+		frame := &aart.Frames[i]
+		rgba := r.RenderCells(frame.Cells, aart.Canvas.Width, aart.Canvas.Height)
+		cur := lookup.indices(rgba)
+		delay := gifDelay(frame.Duration)
+
+		if prev == nil {
+			img := image.NewPaletted(image.Rect(0, 0, width, height), palette)
+			copy(img.Pix, cur)
+			out.Image = append(out.Image, img)
+			out.Delay = append(out.Delay, delay)
+			out.Disposal = append(out.Disposal, gif.DisposalNone)
+			prev = cur
+			continue
+		}
+
+		rect := changedRect(prev, cur, width, height)
+		if rect.Empty() {
+			// Nothing changed: show the previous frame longer
+			out.Delay[len(out.Delay)-1] += delay
+			continue
+		}
+
+		img := image.NewPaletted(rect, palette)
+		for y := rect.Min.Y; y < rect.Max.Y; y++ {
+			for x := rect.Min.X; x < rect.Max.X; x++ {
+				p := y*width + x
+				if cur[p] == prev[p] {
+					img.Pix[img.PixOffset(x, y)] = transparent
+				} else {
+					img.Pix[img.PixOffset(x, y)] = cur[p]
+				}
+			}
+		}
+		out.Image = append(out.Image, img)
+		out.Delay = append(out.Delay, delay)
+		out.Disposal = append(out.Disposal, gif.DisposalNone)
+		prev = cur
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return gif.EncodeAll(file, out)
+}
+
+// frameRange returns the frames an export covers: the selected one, or all
+func frameRange(aart *AartFile, opts ExportOptions) (int, int) {
+	if opts.FrameIndex >= 0 && opts.FrameIndex < len(aart.Frames) {
+		return opts.FrameIndex, opts.FrameIndex + 1
+	}
+	return 0, len(aart.Frames)
+}
+
+// gifDelay converts a frame duration to GIF hundredths of a second.
+// Browsers treat delays under 2 as 10, so that is the floor.
+func gifDelay(ms int) int {
+	if ms <= 0 {
+		ms = DefaultDuration
+	}
+	return max(2, (ms+5)/10)
+}
+
+// changedRect returns the bounding box of the pixels that differ
+func changedRect(prev, cur []uint8, width, height int) image.Rectangle {
+	minX, minY, maxX, maxY := width, height, -1, -1
+	for y := 0; y < height; y++ {
+		row := y * width
+		for x := 0; x < width; x++ {
+			if prev[row+x] != cur[row+x] {
+				minX, maxX = min(minX, x), max(maxX, x)
+				minY, maxY = min(minY, y), max(maxY, y)
+			}
+		}
+	}
+	if maxX < 0 {
+		return image.Rectangle{}
+	}
+	return image.Rect(minX, minY, maxX+1, maxY+1)
+}
+
+// gifPalette builds one palette for all frames. Cells only ever use their
+// foreground and background colors, so the colors are counted per cell
+// rather than per pixel; more than maxGIFColors are reduced by median cut.
+func gifPalette(frames []Frame, r *Rasterizer) color.Palette {
+	counts := map[color.RGBA]int{}
+	for i := range frames {
+		for _, row := range frames[i].Cells {
+			for _, cell := range row {
+				fg, bg := r.color(cell.Foreground, defaultFG), r.color(cell.Background, defaultBG)
+				if r.Monochrome {
+					fg, bg = defaultFG, defaultBG
+				}
+				counts[fg]++
+				counts[bg]++
+			}
+		}
+	}
+	// Blank padding and missing cells
+	counts[defaultFG]++
+	counts[defaultBG]++
+
+	colors := make([]weightedColor, 0, len(counts))
+	for c, n := range counts {
+		colors = append(colors, weightedColor{c, n})
+	}
+	// Map order is random; keep the output deterministic
+	sort.Slice(colors, func(i, j int) bool {
+		a, b := colors[i].c, colors[j].c
+		return uint32(a.R)<<16|uint32(a.G)<<8|uint32(a.B) < uint32(b.R)<<16|uint32(b.G)<<8|uint32(b.B)
+	})
+
+	if len(colors) <= maxGIFColors {
+		palette := make(color.Palette, len(colors))
+		for i, wc := range colors {
+			palette[i] = wc.c
+		}
+		return palette
+	}
+	return medianCut(colors, maxGIFColors)
+}
+
+type weightedColor struct {
+	c     color.RGBA
+	count int
+}
+
+// medianCut reduces colors to at most n by repeatedly splitting the box
+// with the widest channel range at its weighted median
+func medianCut(colors []weightedColor, n int) color.Palette {
+	boxes := [][]weightedColor{colors}
+	for len(boxes) < n {
+		// Pick the box with the widest range that can still be split
+		best, bestRange, bestChannel := -1, -1, 0
+		for i, box := range boxes {
+			if len(box) < 2 {
+				continue
+			}
+			ch, rng := widestChannel(box)
+			if rng > bestRange {
+				best, bestRange, bestChannel = i, rng, ch
+			}
+		}
+		if best < 0 {
+			break
+		}
+
+		box := boxes[best]
+		sort.Slice(box, func(i, j int) bool {
+			return channel(box[i].c, bestChannel) < channel(box[j].c, bestChannel)
+		})
+		total := 0
+		for _, wc := range box {
+			total += wc.count
+		}
+		split, acc := 1, 0
+		for i, wc := range box[:len(box)-1] {
+			acc += wc.count
+			if acc*2 >= total {
+				split = i + 1
+				break
+			}
+		}
+		boxes[best] = box[:split]
+		boxes = append(boxes, box[split:])
+	}
+
+	palette := make(color.Palette, len(boxes))
+	for i, box := range boxes {
+		var r, g, b, total int
+		for _, wc := range box {
+			r += int(wc.c.R) * wc.count
+			g += int(wc.c.G) * wc.count
+			b += int(wc.c.B) * wc.count
+			total += wc.count
+		}
+		palette[i] = color.RGBA{uint8(r / total), uint8(g / total), uint8(b / total), 0xFF}
+	}
+	return palette
+}
+
+func widestChannel(box []weightedColor) (int, int) {
+	lo := [3]int{255, 255, 255}
+	hi := [3]int{}
+	for _, wc := range box {
+		for ch := 0; ch < 3; ch++ {
+			v := int(channel(wc.c, ch))
+			lo[ch], hi[ch] = min(lo[ch], v), max(hi[ch], v)
+		}
+	}
+	best := 0
+	for ch := 1; ch < 3; ch++ {
+		if hi[ch]-lo[ch] > hi[best]-lo[best] {
+			best = ch
+		}
+	}
+	return best, hi[best] - lo[best]
+}
+
+func channel(c color.RGBA, ch int) uint8 {
+	switch ch {
+	case 0:
+		return c.R
+	case 1:
+		return c.G
+	}
+	return c.B
+}
+
+// paletteLookup maps colors to their nearest palette index, caching the
+// few distinct colors a rendered frame has
+type paletteLookup struct {
+	palette color.Palette
+	cache   map[color.RGBA]uint8
+}
+
+func newPaletteLookup(p color.Palette) *paletteLookup {
+	return &paletteLookup{palette: p, cache: make(map[color.RGBA]uint8)}
+}
+
+func (l *paletteLookup) index(c color.RGBA) uint8 {
+	if i, ok := l.cache[c]; ok {
+		return i
+	}
+	i := uint8(l.palette.Index(c))
+	l.cache[c] = i
+	return i
+}
+
+// indices converts an image to palette indices, row by row
+func (l *paletteLookup) indices(img *image.RGBA) []uint8 {
+	b := img.Bounds()
+	out := make([]uint8, b.Dx()*b.Dy())
+	i := 0
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		row := img.Pix[img.PixOffset(b.Min.X, y):]
+		for x := 0; x < b.Dx(); x++ {
+			out[i] = l.index(color.RGBA{row[x*4], row[x*4+1], row[x*4+2], row[x*4+3]})
+			i++
+		}
+	}
+	return out
+}
@@ -0,0 +1,277 @@
+package fileformat
+
+import (
+	"fmt"
+	"go/format"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// goStyleBase is added to a style index to get the rune that stands for it
+// in a frame's Styles, which keeps the first 1792 styles two bytes long and
+// clear of control characters. Indexes that would land on a surrogate skip
+// past them.
+const goStyleBase = 0x100
+
+// goTypePrefix stands for the variable name in goRuntime, whose types are
+// named after it so that several animations can share a package
+const goTypePrefix = "NAME"
+
+// exportGo writes a Go source file declaring the animation as a
+// package-level *<Var>Animation, together with the <Var>Animation type and
+// a small player that only need the standard library. Frames are one
+// string of text each, rows separated by "\n"; with colors, a palette of
+// styles and one rune per cell indexing it. The file is gofmt-clean.
+func exportGo(aart *AartFile, path string, opts ExportOptions) error {
+	start, end := frameRange(aart, opts)
+	if start >= end {
+		return fmt.Errorf("no frames to export")
+	}
+	pkg := opts.GoPackage
+	if pkg == "" {
+		pkg = "main"
+	}
+	if !token.IsIdentifier(pkg) || pkg == "_" {
+		return fmt.Errorf("invalid package name %q", pkg)
+	}
+	name := opts.GoVar
+	if name == "" {
+		name = goIdentifier(strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)))
+	}
+	if !token.IsIdentifier(name) || name == "_" {
+		return fmt.Errorf("invalid variable name %q", name)
+	}
+	width, height := aart.Canvas.Width, aart.Canvas.Height
+
+	type goStyle struct {
+		fg, bg                  uint32
+		bold, italic, underline bool
+	}
+	var palette []goStyle
+	index := map[goStyle]int{}
+	styleRune := func(c Cell) rune {
+		s := goStyle{bold: c.Bold, italic: c.Italic, underline: c.Underline}
+		if r, g, b, ok := ParseHexColor(c.Foreground); ok {
+			s.fg = uint32(r)<<16 | uint32(g)<<8 | uint32(b)
+		}
+		if r, g, b, ok := ParseHexColor(c.Background); ok {
+			s.bg = uint32(r)<<16 | uint32(g)<<8 | uint32(b)
+		}
+		i, ok := index[s]
+		if !ok {
+			i = len(palette)
+			index[s] = i
+			palette = append(palette, s)
+		}
+		r := rune(goStyleBase + i)
+		if r >= 0xD800 {
+			r += 0x800
+		}
+		return r
+	}
+
+	var frames strings.Builder
+	for i := start; i < end; i++ {
+		frame := &aart.Frames[i]
+		frames.WriteString("{\n")
+		var text, styles []string
+		for y := 0; y < height; y++ {
+			var row, rowStyles strings.Builder
+			for x := 0; x < width; x++ {
+				c := cellAt(frame.Cells, x, y)
+				row.WriteRune(goCellRune(c.Char))
+				if opts.Colors {
+					rowStyles.WriteRune(styleRune(c))
+				}
+			}
+			if y < height-1 {
+				row.WriteByte('\n')
+			}
+			text = append(text, strconv.Quote(row.String()))
+			styles = append(styles, strconv.Quote(rowStyles.String()))
+		}
+		frames.WriteString("Text: " + strings.Join(text, " +\n") + ",\n")
+		if opts.Colors {
+			frames.WriteString("Styles: " + strings.Join(styles, " +\n") + ",\n")
+		}
+		frames.WriteString(fmt.Sprintf("Duration: %d * time.Millisecond,\n", max(frame.Duration, MinDuration)))
+		frames.WriteString("},\n")
+	}
+
+	var src strings.Builder
+	src.WriteString(fmt.Sprintf("// Code generated by aart from %s. DO NOT EDIT.\n\n", goComment(aart.Metadata.Title, "an animation")))
+	src.WriteString("package " + pkg + "\n\n")
+	src.WriteString("import (\n\"context\"\n\"fmt\"\n\"io\"\n\"strings\"\n\"time\"\n)\n\n")
+	src.WriteString(fmt.Sprintf("// %s is %s, %dx%d cells, %d frames.\n", name, goComment(aart.Metadata.Title, "the animation"), width, height, end-start))
+	src.WriteString("var " + name + " = &" + name + "Animation{\n")
+	src.WriteString(fmt.Sprintf("Width: %d,\nHeight: %d,\n", width, height))
+	if opts.Colors {
+		src.WriteString("Styles: []" + name + "Style{\n")
+		for _, s := range palette {
+			src.WriteString(fmt.Sprintf("{FG: 0x%06x, BG: 0x%06x", s.fg, s.bg))
+			if s.bold {
+				src.WriteString(", Bold: true")
+			}
+			if s.italic {
+				src.WriteString(", Italic: true")
+			}
+			if s.underline {
+				src.WriteString(", Underline: true")
+			}
+			src.WriteString("},\n")
+		}
+		src.WriteString("},\n")
+	}
+	src.WriteString("Frames: []" + name + "Frame{\n" + frames.String() + "},\n}\n")
+	src.WriteString(strings.ReplaceAll(goRuntime, goTypePrefix, name))
+
+	out, err := format.Source([]byte(src.String()))
+	if err != nil {
+		return fmt.Errorf("generated source does not parse: %w", err)
+	}
+	return os.WriteFile(path, out, 0644)
+}
+
+// goCellRune is the rune a cell is written as: its first rune, with
+// control characters and empty cells as spaces
+func goCellRune(char string) rune {
+	for _, r := range char {
+		if unicode.IsControl(r) {
+			return ' '
+		}
+		return r
+	}
+	return ' '
+}
+
+// goComment quotes a title for a comment, or returns fallback without one
+func goComment(title, fallback string) string {
+	if title = strings.TrimSpace(title); title == "" {
+		return fallback
+	}
+	return strconv.Quote(title)
+}
+
+// goIdentifier turns a file name such as "loading-spinner" into an
+// exported identifier such as "LoadingSpinner"
+func goIdentifier(s string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range s {
+		switch {
+		case unicode.IsLetter(r) || r == '_' || unicode.IsDigit(r) && b.Len() > 0:
+			if upper {
+				r = unicode.ToUpper(r)
+			}
+			b.WriteRune(r)
+			upper = false
+		default:
+			upper = true
+		}
+	}
+	if b.Len() == 0 {
+		return "Anim"
+	}
+	return b.String()
+}
+
+// goRuntime is appended to every generated file, with goTypePrefix
+// replaced by the variable name
+const goRuntime = `
+// NAMEAnimation is a sequence of text frames of Width x Height cells.
+type NAMEAnimation struct {
+	Width, Height int
+	Styles        []NAMEStyle // the styles NAMEFrame.Styles refers to, nil without colors
+	Frames        []NAMEFrame
+}
+
+// NAMEFrame is one frame of a NAMEAnimation. Text holds the rows
+// separated by "\n". Styles, when set, holds a rune per cell: the cell's
+// index in NAMEAnimation.Styles plus 0x100, skipping the surrogates.
+type NAMEFrame struct {
+	Text     string
+	Styles   string
+	Duration time.Duration
+}
+
+// NAMEStyle is the look of a cell, colors as 0xRRGGBB.
+type NAMEStyle struct {
+	FG, BG                  uint32
+	Bold, Italic, Underline bool
+}
+
+// Render returns frame i as text, with ANSI truecolor escape sequences
+// when the animation has colors.
+func (a *NAMEAnimation) Render(i int) string {
+	f := a.Frames[i]
+	if len(a.Styles) == 0 || f.Styles == "" {
+		return f.Text
+	}
+	styles := []rune(f.Styles)
+	var b strings.Builder
+	cell := 0
+	for y, row := range strings.Split(f.Text, "\n") {
+		if y > 0 {
+			b.WriteString("\x1b[0m\n")
+		}
+		last := -1
+		for _, r := range row {
+			n := int(styles[cell]) - 0x100
+			if styles[cell] >= 0xE000 {
+				n -= 0x800
+			}
+			cell++
+			if n != last {
+				s := a.Styles[n]
+				fmt.Fprintf(&b, "\x1b[0;38;2;%d;%d;%d;48;2;%d;%d;%d", s.FG>>16, s.FG>>8&0xFF, s.FG&0xFF, s.BG>>16, s.BG>>8&0xFF, s.BG&0xFF)
+				if s.Bold {
+					b.WriteString(";1")
+				}
+				if s.Italic {
+					b.WriteString(";3")
+				}
+				if s.Underline {
+					b.WriteString(";4")
+				}
+				b.WriteByte('m')
+				last = n
+			}
+			b.WriteRune(r)
+		}
+	}
+	b.WriteString("\x1b[0m")
+	return b.String()
+}
+
+// Play draws the frames in place on w, each for its duration, loops times
+// or forever when loops <= 0, until ctx is done.
+func (a *NAMEAnimation) Play(ctx context.Context, w io.Writer, loops int) error {
+	fmt.Fprint(w, "\x1b[?25l")
+	defer fmt.Fprint(w, "\x1b[0m\x1b[?25h\n")
+	drawn := false
+	for n := 0; loops <= 0 || n < loops; n++ {
+		for i, f := range a.Frames {
+			if drawn {
+				fmt.Fprint(w, "\r")
+				if a.Height > 1 {
+					fmt.Fprintf(w, "\x1b[%dA", a.Height-1)
+				}
+			}
+			if _, err := io.WriteString(w, a.Render(i)); err != nil {
+				return err
+			}
+			drawn = true
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(f.Duration):
+			}
+		}
+	}
+	return nil
+}
+`
@@ -0,0 +1,262 @@
+package fileformat
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// htmlStyle is the look of a run of cells; each distinct one becomes a
+// CSS class
+type htmlStyle struct {
+	fg, bg                  string
+	bold, italic, underline bool
+}
+
+func (s htmlStyle) css() string {
+	var b strings.Builder
+	if s.fg != "" {
+		b.WriteString("color:" + s.fg + ";")
+	}
+	if s.bg != "" {
+		b.WriteString("background:" + s.bg + ";")
+	}
+	if s.bold {
+		b.WriteString("font-weight:bold;")
+	}
+	if s.italic {
+		b.WriteString("font-style:italic;")
+	}
+	if s.underline {
+		b.WriteString("text-decoration:underline;")
+	}
+	return b.String()
+}
+
+// cssColor is a cell color as "#RRGGBB", or "" when it does not parse, so
+// nothing from the file but a color reaches the stylesheet
+func cssColor(color string) string {
+	r, g, b, ok := ParseHexColor(color)
+	if !ok {
+		return ""
+	}
+	return FormatHexColor(r, g, b)
+}
+
+// htmlPlayerData is embedded in the page as JSON. Each frame is either the
+// index of an identical earlier frame, or a list of rows; a row is null
+// when it equals the same row of the previous frame, otherwise a flat list
+// of style index and text pairs, one pair per run of same-styled cells.
+type htmlPlayerData struct {
+	Durations []int `json:"durations"`
+	Frames    []any `json:"frames"`
+}
+
+// exportHTML writes a self-contained page that plays the frames with
+// their durations, with play/pause, seek and loop controls
+func exportHTML(aart *AartFile, path string, opts ExportOptions) error {
+	start, end := frameRange(aart, opts)
+	if start >= end {
+		return fmt.Errorf("no frames to export")
+	}
+	data, styles := encodeHTMLFrames(aart.Frames[start:end], opts.Colors)
+
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	var html strings.Builder
+	html.WriteString("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n")
+	html.WriteString(fmt.Sprintf("<title>%s</title>\n", escapeHTML(aart.Metadata.Title)))
+	html.WriteString("<style>\n")
+	html.WriteString("body { background: #000; color: #FFF; margin: 20px; font-family: sans-serif; }\n")
+	html.WriteString("pre { font-family: monospace; line-height: 1; margin: 0 0 12px; }\n")
+	html.WriteString("#controls { display: flex; gap: 8px; align-items: center; color: #AAA; font-size: 13px; }\n")
+	html.WriteString("#controls input[type=range] { flex: 0 1 320px; }\n")
+	for i, style := range styles {
+		html.WriteString(fmt.Sprintf(".s%d{%s}\n", i, style))
+	}
+	html.WriteString("</style>\n</head>\n<body>\n")
+	html.WriteString("<pre id=\"screen\"></pre>\n")
+	html.WriteString("<div id=\"controls\">\n")
+	html.WriteString("<button id=\"play\">Pause</button>\n")
+	html.WriteString(fmt.Sprintf("<input id=\"seek\" type=\"range\" min=\"0\" max=\"%d\" value=\"0\">\n", len(data.Frames)-1))
+	html.WriteString(fmt.Sprintf("<span id=\"pos\">1/%d</span>\n", len(data.Frames)))
+	html.WriteString("<label><input id=\"loop\" type=\"checkbox\" checked> Loop</label>\n")
+	html.WriteString("</div>\n")
+	// json.Marshal escapes <, > and &, so the payload cannot close the tag
+	html.WriteString("<script id=\"aart-data\" type=\"application/json\">")
+	html.Write(payload)
+	html.WriteString("</script>\n<script>\n")
+	html.WriteString(htmlPlayerScript)
+	html.WriteString("</script>\n</body>\n</html>\n")
+
+	return os.WriteFile(path, []byte(html.String()), 0644)
+}
+
+// encodeHTMLFrames merges runs of same-styled cells and drops repeated
+// rows and frames. It also returns the CSS of each style index.
+func encodeHTMLFrames(frames []Frame, colors bool) (htmlPlayerData, []string) {
+	var data htmlPlayerData
+	var css []string
+	styles := map[htmlStyle]int{}
+	styleIndex := func(c Cell) int {
+		s := htmlStyle{bold: c.Bold, italic: c.Italic, underline: c.Underline}
+		if colors {
+			s.fg, s.bg = cssColor(c.Foreground), cssColor(c.Background)
+		}
+		i, ok := styles[s]
+		if !ok {
+			i = len(css)
+			styles[s] = i
+			css = append(css, s.css())
+		}
+		return i
+	}
+
+	seen := map[string]int{} // encoded frame -> first index
+	var prevRows []string
+	for i := range frames {
+		frame := &frames[i]
+		data.Durations = append(data.Durations, max(frame.Duration, MinDuration))
+
+		rows := make([]any, len(frame.Cells))
+		keys := make([]string, len(frame.Cells))
+		for y, row := range frame.Cells {
+			var runs []any
+			var text strings.Builder
+			style := -1
+			for _, cell := range row {
+				s := styleIndex(cell)
+				if s != style && style >= 0 {
+					runs = append(runs, style, text.String())
+					text.Reset()
+				}
+				style = s
+				if cell.Char == "" {
+					text.WriteByte(' ')
+				} else {
+					text.WriteString(cell.Char)
+				}
+			}
+			if style >= 0 {
+				runs = append(runs, style, text.String())
+			}
+			key, _ := json.Marshal(runs)
+			keys[y] = string(key)
+			rows[y] = runs
+			if y < len(prevRows) && prevRows[y] == keys[y] {
+				rows[y] = nil
+			}
+		}
+
+		whole := strings.Join(keys, "\n")
+		if first, ok := seen[whole]; ok {
+			data.Frames = append(data.Frames, first)
+		} else {
+			seen[whole] = i
+			data.Frames = append(data.Frames, rows)
+		}
+		prevRows = keys
+	}
+	return data, css
+}
+
+// htmlPlayerScript decodes the embedded frames and plays them
+const htmlPlayerScript = `(function () {
+  var data = JSON.parse(document.getElementById('aart-data').textContent);
+  var screen = document.getElementById('screen');
+  var play = document.getElementById('play');
+  var seek = document.getElementById('seek');
+  var pos = document.getElementById('pos');
+  var loop = document.getElementById('loop');
+  var n = data.frames.length;
+
+  function esc(s) {
+    return s.replace(/[&<>]/g, function (c) {
+      return c === '&' ? '&amp;' : c === '<' ? '&lt;' : '&gt;';
+    });
+  }
+  function rowHTML(runs) {
+    var h = '';
+    for (var k = 0; k < runs.length; k += 2) {
+      h += '<span class="s' + runs[k] + '">' + esc(runs[k + 1]) + '</span>';
+    }
+    return h;
+  }
+
+  // Expand every frame into row HTML, reusing repeated rows and frames
+  var frames = [];
+  for (var i = 0; i < n; i++) {
+    var f = data.frames[i];
+    if (typeof f === 'number') {
+      frames.push(frames[f]);
+      continue;
+    }
+    var prev = frames[i - 1] || [];
+    frames.push(f.map(function (runs, y) {
+      return runs === null ? prev[y] : rowHTML(runs);
+    }));
+  }
+
+  var current = 0, playing = n > 1, timer = null;
+
+  function show(i) {
+    current = i;
+    screen.innerHTML = frames[i].join('\n');
+    seek.value = i;
+    pos.textContent = (i + 1) + '/' + n;
+  }
+  function update() {
+    play.textContent = playing ? 'Pause' : 'Play';
+  }
+  function schedule() {
+    clearTimeout(timer);
+    if (!playing) return;
+    timer = setTimeout(function () {
+      var next = current + 1;
+      if (next >= n) {
+        if (!loop.checked) {
+          playing = false;
+          update();
+          return;
+        }
+        next = 0;
+      }
+      show(next);
+      schedule();
+    }, data.durations[current]);
+  }
+
+  play.onclick = function () {
+    playing = !playing;
+    if (playing && current === n - 1 && !loop.checked) show(0);
+    update();
+    schedule();
+  };
+  seek.oninput = function () {
+    show(+seek.value);
+    schedule();
+  };
+  document.addEventListener('keydown', function (e) {
+    if (e.target.tagName === 'INPUT' && e.target.type === 'range') return;
+    if (e.key === ' ') {
+      e.preventDefault();
+      play.onclick();
+    } else if (e.key === 'ArrowRight') {
+      show((current + 1) % n);
+      schedule();
+    } else if (e.key === 'ArrowLeft') {
+      show((current + n - 1) % n);
+      schedule();
+    }
+  });
+
+  if (n < 2) document.getElementById('controls').style.display = 'none';
+  show(0);
+  update();
+  schedule();
+})();
+`
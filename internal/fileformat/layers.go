@@ -0,0 +1,215 @@
+package fileformat
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Layer blend modes
+const (
+	BlendNormal    = "normal"     // opaque cells cover the layers below
+	BlendMultiply  = "multiply"   // colors are multiplied with the layers below
+	BlendCharOnly  = "char-only"  // only the character is replaced
+	BlendColorOnly = "color-only" // only the colors are replaced
+)
+
+// BlendModes lists the supported blend modes
+var BlendModes = []string{BlendNormal, BlendMultiply, BlendCharOnly, BlendColorOnly}
+
+// FrameLayer holds the cells of one layer in one frame. Frame.Layers is
+// indexed like AartFile.Layers, bottom layer first.
+type FrameLayer struct {
+	Cells [][]Cell    `json:"cells,omitempty"`
+	Delta []CellDelta `json:"delta,omitempty"` // changed cells in a delta frame
+}
+
+// IsTransparent reports whether a layer cell lets the layers below show
+// through: a blank character with no background color
+func (c Cell) IsTransparent() bool {
+	return (c.Char == "" || c.Char == " ") && c.Background == ""
+}
+
+// blankCell is what the composite shows where no layer draws anything
+var blankCell = Cell{Char: " ", Foreground: "#FFFFFF", Background: "#000000"}
+
+// transparentCell is an empty cell of an upper layer
+var transparentCell = Cell{Char: " "}
+
+// CompositeCell draws src over dst using the layer's opacity and blend mode
+func CompositeCell(dst, src Cell, layer Layer) Cell {
+	if !layer.Visible || layer.Opacity <= 0 {
+		return dst
+	}
+	opacity := math.Min(layer.Opacity, 1)
+	srcBlank := src.Char == "" || src.Char == " "
+
+	out := dst
+	switch layer.BlendMode {
+	case BlendCharOnly:
+		if srcBlank || opacity < 0.5 {
+			return dst
+		}
+		out.Char = src.Char
+		out.Bold, out.Italic, out.Underline = src.Bold, src.Italic, src.Underline
+
+	case BlendColorOnly:
+		if src.Foreground != "" {
+			out.Foreground = BlendColor(dst.Foreground, src.Foreground, opacity)
+		}
+		if src.Background != "" {
+			out.Background = BlendColor(dst.Background, src.Background, opacity)
+		}
+
+	case BlendMultiply:
+		if src.IsTransparent() {
+			return dst
+		}
+		if !srcBlank && opacity >= 0.5 {
+			out.Char = src.Char
+		}
+		if src.Foreground != "" {
+			out.Foreground = BlendColor(dst.Foreground, MultiplyColor(dst.Foreground, src.Foreground), opacity)
+		}
+		if src.Background != "" {
+			out.Background = BlendColor(dst.Background, MultiplyColor(dst.Background, src.Background), opacity)
+		}
+
+	default: // BlendNormal
+		if src.IsTransparent() {
+			return dst
+		}
+		if opacity >= 0.5 {
+			out.Char = src.Char
+			out.Bold, out.Italic, out.Underline = src.Bold, src.Italic, src.Underline
+		}
+		if src.Foreground != "" {
+			out.Foreground = BlendColor(dst.Foreground, src.Foreground, opacity)
+		}
+		if src.Background != "" {
+			out.Background = BlendColor(dst.Background, src.Background, opacity)
+		}
+	}
+	return out
+}
+
+// Composite flattens per-layer grids into a single grid. grids is indexed
+// like layers; missing grids or cells are treated as transparent.
+func Composite(layers []Layer, grids [][][]Cell, width, height int) [][]Cell {
+	out := make([][]Cell, height)
+	for y := 0; y < height; y++ {
+		out[y] = make([]Cell, width)
+		for x := 0; x < width; x++ {
+			out[y][x] = blankCell
+		}
+	}
+
+	for i, grid := range grids {
+		if i >= len(layers) {
+			break
+		}
+		layer := layers[i]
+		for y := 0; y < height && y < len(grid); y++ {
+			for x := 0; x < width && x < len(grid[y]); x++ {
+				out[y][x] = CompositeCell(out[y][x], grid[y][x], layer)
+			}
+		}
+	}
+	return out
+}
+
+// LayerGrids returns the per-layer grids of a frame. Frames saved before
+// layers carried cell data only have Cells, which becomes the bottom layer.
+func (a *AartFile) LayerGrids(frame *Frame) [][][]Cell {
+	if len(frame.Layers) > 0 {
+		grids := make([][][]Cell, len(frame.Layers))
+		for i, l := range frame.Layers {
+			grids[i] = l.Cells
+		}
+		return grids
+	}
+	return [][][]Cell{frame.Cells}
+}
+
+// Flatten recomputes every frame's Cells from its layer data, so readers
+// that ignore layers still see the composited picture
+func (a *AartFile) Flatten() {
+	for i := range a.Frames {
+		frame := &a.Frames[i]
+		if len(frame.Layers) == 0 {
+			continue
+		}
+		frame.Cells = Composite(a.Layers, a.LayerGrids(frame), a.Canvas.Width, a.Canvas.Height)
+	}
+}
+
+// ValidBlendMode reports whether mode is a supported blend mode
+func ValidBlendMode(mode string) bool {
+	for _, m := range BlendModes {
+		if m == mode {
+			return true
+		}
+	}
+	return false
+}
+
+// BlendColor mixes two hex colors, t=0 gives a and t=1 gives b
+func BlendColor(a, b string, t float64) string {
+	if t >= 1 || a == "" {
+		return b
+	}
+	if t <= 0 {
+		return a
+	}
+	ar, ag, ab, ok1 := ParseHexColor(a)
+	br, bg, bb, ok2 := ParseHexColor(b)
+	if !ok1 || !ok2 {
+		return b
+	}
+	mix := func(x, y uint8) uint8 {
+		return uint8(math.Round(float64(x)*(1-t) + float64(y)*t))
+	}
+	return FormatHexColor(mix(ar, br), mix(ag, bg), mix(ab, bb))
+}
+
+// MultiplyColor multiplies two hex colors channel by channel
+func MultiplyColor(a, b string) string {
+	ar, ag, ab, ok1 := ParseHexColor(a)
+	br, bg, bb, ok2 := ParseHexColor(b)
+	if !ok1 {
+		return b
+	}
+	if !ok2 {
+		return a
+	}
+	mul := func(x, y uint8) uint8 {
+		return uint8(uint16(x) * uint16(y) / 255)
+	}
+	return FormatHexColor(mul(ar, br), mul(ag, bg), mul(ab, bb))
+}
+
+// ParseHexColor parses "#RRGGBB" (or "#RGB") into its channels
+func ParseHexColor(hex string) (r, g, b uint8, ok bool) {
+	hex = strings.TrimPrefix(hex, "#")
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	if len(hex) != 6 {
+		return 0, 0, 0, false
+	}
+	var v [3]uint8
+	for i := 0; i < 3; i++ {
+		n, err := strconv.ParseUint(hex[i*2:i*2+2], 16, 8)
+		if err != nil {
+			return 0, 0, 0, false
+		}
+		v[i] = uint8(n)
+	}
+	return v[0], v[1], v[2], true
+}
+
+// FormatHexColor formats channels as "#RRGGBB"
+func FormatHexColor(r, g, b uint8) string {
+	return fmt.Sprintf("#%02X%02X%02X", r, g, b)
+}
@@ -0,0 +1,252 @@
+package fileformat
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CurrentVersion is the schema version written by Save
+const CurrentVersion = "1.1"
+
+// legacyVersion is assumed for files without a version field
+const legacyVersion = "1.0"
+
+// ErrNewerVersion is returned for files written by a newer version of aart
+var ErrNewerVersion = errors.New("file was written by a newer version of aart")
+
+// migration upgrades a raw document from one schema version to the next
+type migration struct {
+	from, to string
+	apply    func(doc map[string]any) error
+}
+
+// migrations is the upgrade chain, oldest first. Each step must produce a
+// document the next one accepts; the last one ends at CurrentVersion.
+var migrations = []migration{
+	{from: "1.0", to: "1.1", apply: migrate10to11},
+}
+
+// timestampLayouts are the timestamp formats found in older files
+var timestampLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+	time.RFC1123Z,
+	time.RFC1123,
+	time.UnixDate,
+}
+
+// migrate10to11 normalizes what the 1.0 writers left behind: timestamps as
+// free-form strings or unix seconds, missing metadata, and frames without
+// an index or duration
+func migrate10to11(doc map[string]any) error {
+	meta, _ := doc["metadata"].(map[string]any)
+	if meta == nil {
+		meta = map[string]any{}
+		doc["metadata"] = meta
+	}
+	if _, ok := meta["title"].(string); !ok {
+		meta["title"] = ""
+	}
+	for _, key := range []string{"created", "modified"} {
+		meta[key] = normalizeTimestamp(meta[key])
+	}
+
+	frames, _ := doc["frames"].([]any)
+	for i, f := range frames {
+		frame, ok := f.(map[string]any)
+		if !ok {
+			return fmt.Errorf("frame %d is not an object", i)
+		}
+		if _, ok := frame["index"]; !ok {
+			frame["index"] = float64(i)
+		}
+		if _, ok := frame["duration"]; !ok {
+			frame["duration"] = float64(0)
+		}
+	}
+	return nil
+}
+
+// normalizeTimestamp converts an old timestamp to RFC 3339; values that
+// cannot be read become the zero time
+func normalizeTimestamp(v any) string {
+	var t time.Time
+	switch v := v.(type) {
+	case string:
+		s := strings.TrimSpace(v)
+		for _, layout := range timestampLayouts {
+			if parsed, err := time.Parse(layout, s); err == nil {
+				t = parsed
+				break
+			}
+		}
+		if t.IsZero() {
+			if secs, err := strconv.ParseInt(s, 10, 64); err == nil {
+				t = time.Unix(secs, 0).UTC()
+			}
+		}
+	case float64:
+		t = time.Unix(int64(v), 0).UTC()
+	}
+	return t.Format(time.RFC3339Nano)
+}
+
+// ParseVersion splits a "major.minor" version string
+func ParseVersion(v string) (major, minor int, err error) {
+	maj, mnr, ok := strings.Cut(v, ".")
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid version %q", v)
+	}
+	if major, err = strconv.Atoi(maj); err != nil {
+		return 0, 0, fmt.Errorf("invalid version %q", v)
+	}
+	if minor, err = strconv.Atoi(mnr); err != nil {
+		return 0, 0, fmt.Errorf("invalid version %q", v)
+	}
+	return major, minor, nil
+}
+
+// CompareVersions returns -1, 0 or 1 as a is older, equal or newer than b
+func CompareVersions(a, b string) (int, error) {
+	amaj, amin, err := ParseVersion(a)
+	if err != nil {
+		return 0, err
+	}
+	bmaj, bmin, err := ParseVersion(b)
+	if err != nil {
+		return 0, err
+	}
+	if amaj != bmaj {
+		return cmpInt(amaj, bmaj), nil
+	}
+	return cmpInt(amin, bmin), nil
+}
+
+func cmpInt(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// CheckVersion rejects files written by a newer version of aart
+func CheckVersion(v string) error {
+	if v == "" {
+		v = legacyVersion
+	}
+	c, err := CompareVersions(v, CurrentVersion)
+	if err != nil {
+		return err
+	}
+	if c > 0 {
+		return fmt.Errorf("%w: version %s, this build supports up to %s", ErrNewerVersion, v, CurrentVersion)
+	}
+	return nil
+}
+
+// documentVersion reads the version of a raw document
+func documentVersion(doc map[string]any) (string, error) {
+	v, ok := doc["version"]
+	if !ok || v == nil {
+		return legacyVersion, nil
+	}
+	switch v := v.(type) {
+	case string:
+		if v == "" {
+			return legacyVersion, nil
+		}
+		return v, nil
+	case float64:
+		// Hand-written files sometimes have "version": 1.0
+		return strconv.FormatFloat(v, 'f', 1, 64), nil
+	}
+	return "", fmt.Errorf("invalid version %v", v)
+}
+
+// Migrate upgrades a raw JSON document to CurrentVersion in place and
+// returns the version it started at
+func Migrate(doc map[string]any) (string, error) {
+	from, err := documentVersion(doc)
+	if err != nil {
+		return "", err
+	}
+	if err := CheckVersion(from); err != nil {
+		return from, err
+	}
+
+	v := from
+	for _, m := range migrations {
+		c, err := CompareVersions(v, m.from)
+		if err != nil {
+			return from, err
+		}
+		if c > 0 {
+			continue
+		}
+		if err := m.apply(doc); err != nil {
+			return from, fmt.Errorf("migrating %s to %s: %w", m.from, m.to, err)
+		}
+		v = m.to
+	}
+	doc["version"] = CurrentVersion
+	return from, nil
+}
+
+// peekVersion reads only the version field of a JSON file
+func peekVersion(data []byte) (string, error) {
+	var head struct {
+		Version json.RawMessage `json:"version"`
+	}
+	if err := json.Unmarshal(data, &head); err != nil {
+		return "", err
+	}
+	var v string
+	if len(head.Version) == 0 || json.Unmarshal(head.Version, &v) != nil || v == "" {
+		// Missing or non-string: let Migrate sort it out
+		return "", nil
+	}
+	return v, nil
+}
+
+// decodeJSON parses a JSON .aart document, migrating it first when it was
+// written by an older version
+func decodeJSON(data []byte) (*AartFile, error) {
+	v, err := peekVersion(data)
+	if err != nil {
+		return nil, err
+	}
+	if v != "" {
+		if err := CheckVersion(v); err != nil {
+			return nil, err
+		}
+	}
+
+	if v != CurrentVersion {
+		var doc map[string]any
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return nil, err
+		}
+		if _, err := Migrate(doc); err != nil {
+			return nil, err
+		}
+		if data, err = json.Marshal(doc); err != nil {
+			return nil, err
+		}
+	}
+
+	var aart AartFile
+	if err := json.Unmarshal(data, &aart); err != nil {
+		return nil, err
+	}
+	return &aart, nil
+}
@@ -0,0 +1,588 @@
+package fileformat
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// The packed container stores the same data as the JSON format in a much
+// smaller file:
+//
+//	magic "AARTPACK" | version | compression
+//	frame blocks, each compressed on its own
+//	directory block (compressed)
+//	footer: directory offset (u64) | directory length (u32) | magic
+//
+// The directory holds the file without cell data, the frame index, a
+// deduplicated color table and a cell table. Frame blocks store every grid
+// (the composite cells, then each layer) as run-length-encoded rows of
+// cell table indices, or for delta frames the changed cells of every grid.
+// Frames can be decoded one at a time with PackedReader.
+
+// PackedMagic starts and ends every packed container
+const PackedMagic = "AARTPACK"
+
+// PackedExt is the file extension that selects the packed container
+const PackedExt = ".aartz"
+
+const packedVersion = 1
+
+// Compression is the compression used for packed blocks
+type Compression byte
+
+const (
+	CompressionNone Compression = 0
+	CompressionGzip Compression = 1
+)
+
+const (
+	packedHeaderSize = len(PackedMagic) + 2
+	packedFooterSize = 8 + 4 + len(PackedMagic)
+	maxRunLength     = 1 << 20 // sanity limit when decoding
+)
+
+// Cell attribute bits in the cell table
+const (
+	attrBold = 1 << iota
+	attrItalic
+	attrUnderline
+)
+
+// packedDirectory is the JSON part of the directory block
+type packedDirectory struct {
+	File   AartFile           `json:"file"` // frames carry no cells
+	Frames []packedFrameEntry `json:"frames"`
+}
+
+// packedFrameEntry locates a frame block
+type packedFrameEntry struct {
+	Offset int64 `json:"offset"`
+	Length int   `json:"length"`
+	Layers int   `json:"layers"`
+	Delta  bool  `json:"delta,omitempty"` // block holds changes to the previous frame
+}
+
+// IsPacked reports whether data starts with the packed container magic
+func IsPacked(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(PackedMagic))
+}
+
+// cellTable deduplicates cells and colors while encoding
+type cellTable struct {
+	colors     []string
+	colorIndex map[string]int
+	cells      []packedCell
+	cellIndex  map[packedCell]int
+}
+
+type packedCell struct {
+	char   string
+	fg, bg int
+	attrs  byte
+}
+
+func newCellTable() *cellTable {
+	return &cellTable{colorIndex: make(map[string]int), cellIndex: make(map[packedCell]int)}
+}
+
+func (t *cellTable) color(c string) int {
+	if i, ok := t.colorIndex[c]; ok {
+		return i
+	}
+	t.colorIndex[c] = len(t.colors)
+	t.colors = append(t.colors, c)
+	return len(t.colors) - 1
+}
+
+func (t *cellTable) index(c Cell) int {
+	pc := packedCell{char: c.Char, fg: t.color(c.Foreground), bg: t.color(c.Background)}
+	if c.Bold {
+		pc.attrs |= attrBold
+	}
+	if c.Italic {
+		pc.attrs |= attrItalic
+	}
+	if c.Underline {
+		pc.attrs |= attrUnderline
+	}
+	if i, ok := t.cellIndex[pc]; ok {
+		return i
+	}
+	t.cellIndex[pc] = len(t.cells)
+	t.cells = append(t.cells, pc)
+	return len(t.cells) - 1
+}
+
+// encodePacked writes aart as a packed container
+func encodePacked(aart *AartFile, compression Compression) ([]byte, error) {
+	var out bytes.Buffer
+	out.WriteString(PackedMagic)
+	out.WriteByte(packedVersion)
+	out.WriteByte(byte(compression))
+
+	table := newCellTable()
+	dir := packedDirectory{File: *aart}
+	dir.File.Frames = make([]Frame, len(aart.Frames))
+	dir.Frames = make([]packedFrameEntry, len(aart.Frames))
+
+	for i := range aart.Frames {
+		frame := &aart.Frames[i]
+		var raw []byte
+		if frame.IsDelta() {
+			raw = appendDelta(raw, frame.Delta, table)
+			for _, l := range frame.Layers {
+				raw = appendDelta(raw, l.Delta, table)
+			}
+		} else {
+			raw = appendGrid(raw, frame.Cells, table)
+			for _, l := range frame.Layers {
+				raw = appendGrid(raw, l.Cells, table)
+			}
+		}
+		block, err := compress(raw, compression)
+		if err != nil {
+			return nil, err
+		}
+
+		dir.File.Frames[i] = Frame{Index: frame.Index, Duration: frame.Duration, Name: frame.Name}
+		dir.Frames[i] = packedFrameEntry{
+			Offset: int64(out.Len()),
+			Length: len(block),
+			Layers: len(frame.Layers),
+			Delta:  frame.IsDelta(),
+		}
+		out.Write(block)
+	}
+
+	meta, err := json.Marshal(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal directory: %w", err)
+	}
+	raw := binary.AppendUvarint(nil, uint64(len(meta)))
+	raw = append(raw, meta...)
+	raw = binary.AppendUvarint(raw, uint64(len(table.colors)))
+	for _, c := range table.colors {
+		raw = appendString(raw, c)
+	}
+	raw = binary.AppendUvarint(raw, uint64(len(table.cells)))
+	for _, c := range table.cells {
+		raw = appendString(raw, c.char)
+		raw = binary.AppendUvarint(raw, uint64(c.fg))
+		raw = binary.AppendUvarint(raw, uint64(c.bg))
+		raw = append(raw, c.attrs)
+	}
+	block, err := compress(raw, compression)
+	if err != nil {
+		return nil, err
+	}
+
+	dirOffset := out.Len()
+	out.Write(block)
+	var footer [12]byte
+	binary.LittleEndian.PutUint64(footer[0:8], uint64(dirOffset))
+	binary.LittleEndian.PutUint32(footer[8:12], uint32(len(block)))
+	out.Write(footer[:])
+	out.WriteString(PackedMagic)
+	return out.Bytes(), nil
+}
+
+// appendGrid encodes a grid as its row count followed by run-length-encoded
+// rows: a run count, then (length, cell index) pairs
+func appendGrid(b []byte, grid [][]Cell, table *cellTable) []byte {
+	b = binary.AppendUvarint(b, uint64(len(grid)))
+	for _, row := range grid {
+		var runs [][2]int
+		for _, c := range row {
+			idx := table.index(c)
+			if n := len(runs); n > 0 && runs[n-1][1] == idx {
+				runs[n-1][0]++
+				continue
+			}
+			runs = append(runs, [2]int{1, idx})
+		}
+		b = binary.AppendUvarint(b, uint64(len(runs)))
+		for _, r := range runs {
+			b = binary.AppendUvarint(b, uint64(r[0]))
+			b = binary.AppendUvarint(b, uint64(r[1]))
+		}
+	}
+	return b
+}
+
+// appendDelta encodes changed cells as a count followed by
+// (x, y, cell index) triples
+func appendDelta(b []byte, delta []CellDelta, table *cellTable) []byte {
+	b = binary.AppendUvarint(b, uint64(len(delta)))
+	for _, d := range delta {
+		b = binary.AppendUvarint(b, uint64(d.X))
+		b = binary.AppendUvarint(b, uint64(d.Y))
+		b = binary.AppendUvarint(b, uint64(table.index(d.Cell)))
+	}
+	return b
+}
+
+func appendString(b []byte, s string) []byte {
+	b = binary.AppendUvarint(b, uint64(len(s)))
+	return append(b, s...)
+}
+
+func compress(data []byte, compression Compression) ([]byte, error) {
+	switch compression {
+	case CompressionNone:
+		return data, nil
+	case CompressionGzip:
+		var buf bytes.Buffer
+		w, err := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write(data); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	default:
+		return nil, fmt.Errorf("unsupported compression %d", compression)
+	}
+}
+
+func decompress(data []byte, compression Compression) ([]byte, error) {
+	switch compression {
+	case CompressionNone:
+		return data, nil
+	case CompressionGzip:
+		r, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		return io.ReadAll(r)
+	default:
+		return nil, fmt.Errorf("unsupported compression %d", compression)
+	}
+}
+
+// PackedReader reads a packed container. The directory is read up front,
+// frames are decoded on demand.
+type PackedReader struct {
+	r           io.ReaderAt
+	closer      io.Closer
+	compression Compression
+	dir         packedDirectory
+	cells       []Cell
+
+	// last decoded frame, the base for sequential delta frames
+	last      *Frame
+	lastIndex int
+}
+
+// OpenPacked opens a packed container file for lazy reading
+func OpenPacked(path string) (*PackedReader, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to stat file: %w", err)
+	}
+	p, err := NewPackedReader(f, info.Size())
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	p.closer = f
+	return p, nil
+}
+
+// NewPackedReader reads the directory of a packed container of the given size
+func NewPackedReader(r io.ReaderAt, size int64) (*PackedReader, error) {
+	if size < int64(packedHeaderSize+packedFooterSize) {
+		return nil, errors.New("packed file too short")
+	}
+	header := make([]byte, packedHeaderSize)
+	if _, err := r.ReadAt(header, 0); err != nil {
+		return nil, fmt.Errorf("failed to read header: %w", err)
+	}
+	if !IsPacked(header) {
+		return nil, errors.New("not a packed .aart file")
+	}
+	if header[len(PackedMagic)] != packedVersion {
+		return nil, fmt.Errorf("unsupported packed version %d", header[len(PackedMagic)])
+	}
+	p := &PackedReader{r: r, compression: Compression(header[len(PackedMagic)+1])}
+
+	footer := make([]byte, packedFooterSize)
+	if _, err := r.ReadAt(footer, size-int64(packedFooterSize)); err != nil {
+		return nil, fmt.Errorf("failed to read footer: %w", err)
+	}
+	if string(footer[12:]) != PackedMagic {
+		return nil, errors.New("packed file is truncated")
+	}
+	offset := int64(binary.LittleEndian.Uint64(footer[0:8]))
+	length := int64(binary.LittleEndian.Uint32(footer[8:12]))
+	if offset < int64(packedHeaderSize) || offset+length > size-int64(packedFooterSize) {
+		return nil, errors.New("packed directory out of bounds")
+	}
+
+	raw, err := p.readBlock(offset, length)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read directory: %w", err)
+	}
+	if err := p.parseDirectory(raw); err != nil {
+		return nil, fmt.Errorf("failed to parse directory: %w", err)
+	}
+	return p, nil
+}
+
+func (p *PackedReader) readBlock(offset, length int64) ([]byte, error) {
+	block := make([]byte, length)
+	if _, err := p.r.ReadAt(block, offset); err != nil {
+		return nil, err
+	}
+	return decompress(block, p.compression)
+}
+
+func (p *PackedReader) parseDirectory(raw []byte) error {
+	d := decoder{buf: raw}
+	meta := d.bytes()
+	if d.err != nil {
+		return d.err
+	}
+	if err := json.Unmarshal(meta, &p.dir); err != nil {
+		return err
+	}
+	if err := CheckVersion(p.dir.File.Version); err != nil {
+		return err
+	}
+	if len(p.dir.Frames) != len(p.dir.File.Frames) {
+		return errors.New("frame index does not match frames")
+	}
+
+	colors := make([]string, d.count())
+	for i := range colors {
+		colors[i] = string(d.bytes())
+	}
+	p.cells = make([]Cell, d.count())
+	for i := range p.cells {
+		char := string(d.bytes())
+		fg, bg := d.uvarint(), d.uvarint()
+		attrs := d.byte()
+		if d.err != nil {
+			break
+		}
+		if fg >= uint64(len(colors)) || bg >= uint64(len(colors)) {
+			return errors.New("color index out of range")
+		}
+		p.cells[i] = Cell{
+			Char:       char,
+			Foreground: colors[fg],
+			Background: colors[bg],
+			Bold:       attrs&attrBold != 0,
+			Italic:     attrs&attrItalic != 0,
+			Underline:  attrs&attrUnderline != 0,
+		}
+	}
+	return d.err
+}
+
+// Header returns the file without cell data: metadata, canvas, layers,
+// palette, audio, and frames with their timing and names
+func (p *PackedReader) Header() *AartFile {
+	a := p.dir.File
+	a.Frames = append([]Frame(nil), p.dir.File.Frames...)
+	return &a
+}
+
+// FrameCount returns the number of frames
+func (p *PackedReader) FrameCount() int {
+	return len(p.dir.Frames)
+}
+
+// Frame decodes frame i. Delta frames are rebuilt from the nearest
+// keyframe, or from the last decoded frame when reading sequentially. The
+// returned cells are shared with that cache and must not be modified.
+func (p *PackedReader) Frame(i int) (*Frame, error) {
+	if i < 0 || i >= len(p.dir.Frames) {
+		return nil, fmt.Errorf("frame %d out of range", i)
+	}
+
+	start := i
+	for start > 0 && p.dir.Frames[start].Delta {
+		start--
+	}
+	var frame *Frame
+	if p.last != nil && p.lastIndex >= start && p.lastIndex <= i {
+		frame, start = p.last, p.lastIndex+1
+	}
+
+	for j := start; j <= i; j++ {
+		f, err := p.decodeBlock(j)
+		if err != nil {
+			return nil, fmt.Errorf("frame %d: %w", j, err)
+		}
+		if f.IsDelta() {
+			if frame == nil {
+				return nil, fmt.Errorf("frame %d: delta frame without a previous frame", j)
+			}
+			if err := f.applyTo(frame); err != nil {
+				return nil, fmt.Errorf("frame %d: %w", j, err)
+			}
+		}
+		frame = f
+	}
+
+	p.last, p.lastIndex = frame, i
+	return frame, nil
+}
+
+// decodeBlock decodes the block of frame i as stored, full or delta
+func (p *PackedReader) decodeBlock(i int) (*Frame, error) {
+	entry := p.dir.Frames[i]
+	raw, err := p.readBlock(entry.Offset, int64(entry.Length))
+	if err != nil {
+		return nil, err
+	}
+
+	frame := p.dir.File.Frames[i]
+	d := decoder{buf: raw}
+	if entry.Delta {
+		frame.Encoding = EncodingDelta
+		frame.Delta = p.delta(&d)
+		for l := 0; l < entry.Layers && d.err == nil; l++ {
+			frame.Layers = append(frame.Layers, FrameLayer{Delta: p.delta(&d)})
+		}
+	} else {
+		frame.Cells = p.grid(&d)
+		for l := 0; l < entry.Layers && d.err == nil; l++ {
+			frame.Layers = append(frame.Layers, FrameLayer{Cells: p.grid(&d)})
+		}
+	}
+	if d.err != nil {
+		return nil, d.err
+	}
+	return &frame, nil
+}
+
+// delta decodes a list of changed cells: a count, then (x, y, cell index)
+func (p *PackedReader) delta(d *decoder) []CellDelta {
+	delta := make([]CellDelta, d.count())
+	for i := range delta {
+		x, y, idx := d.uvarint(), d.uvarint(), d.uvarint()
+		if d.err != nil {
+			break
+		}
+		if idx >= uint64(len(p.cells)) || x > maxRunLength || y > maxRunLength {
+			d.err = errors.New("corrupt delta")
+			break
+		}
+		delta[i] = CellDelta{X: int(x), Y: int(y), Cell: p.cells[idx]}
+	}
+	return delta
+}
+
+// grid decodes one run-length-encoded grid
+func (p *PackedReader) grid(d *decoder) [][]Cell {
+	grid := make([][]Cell, d.count())
+	for y := range grid {
+		grid[y] = []Cell{}
+		runs := d.count()
+		for r := 0; r < runs && d.err == nil; r++ {
+			n, idx := d.uvarint(), d.uvarint()
+			if idx >= uint64(len(p.cells)) || n > maxRunLength {
+				d.err = errors.New("corrupt row")
+				break
+			}
+			for ; n > 0; n-- {
+				grid[y] = append(grid[y], p.cells[idx])
+			}
+		}
+	}
+	return grid
+}
+
+// ReadAll decodes every frame into a complete AartFile. The directory is
+// typed, so older packed files need no migration beyond the version bump.
+func (p *PackedReader) ReadAll() (*AartFile, error) {
+	a := p.Header()
+	a.Version = CurrentVersion
+	for i := range a.Frames {
+		frame, err := p.Frame(i)
+		if err != nil {
+			return nil, err
+		}
+		a.Frames[i] = *frame
+	}
+	return a, nil
+}
+
+// Close closes the underlying file when the reader was opened by path
+func (p *PackedReader) Close() error {
+	if p.closer != nil {
+		return p.closer.Close()
+	}
+	return nil
+}
+
+// decoder reads uvarint-based values and remembers the first error
+type decoder struct {
+	buf []byte
+	err error
+}
+
+func (d *decoder) uvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(d.buf)
+	if n <= 0 {
+		d.err = errors.New("unexpected end of data")
+		return 0
+	}
+	d.buf = d.buf[n:]
+	return v
+}
+
+// count reads a length that must fit in the remaining data
+func (d *decoder) count() int {
+	v := d.uvarint()
+	if v > uint64(len(d.buf)) {
+		if d.err == nil {
+			d.err = errors.New("length out of range")
+		}
+		return 0
+	}
+	return int(v)
+}
+
+func (d *decoder) bytes() []byte {
+	n := d.count()
+	if d.err != nil {
+		return nil
+	}
+	b := d.buf[:n]
+	d.buf = d.buf[n:]
+	return b
+}
+
+func (d *decoder) byte() byte {
+	if d.err != nil {
+		return 0
+	}
+	if len(d.buf) == 0 {
+		d.err = errors.New("unexpected end of data")
+		return 0
+	}
+	b := d.buf[0]
+	d.buf = d.buf[1:]
+	return b
+}
@@ -0,0 +1,88 @@
+package fileformat
+
+// ansi16 is the xterm palette of the 16 basic ANSI colors: black, red,
+// green, yellow, blue, magenta, cyan, white, then their bright variants
+var ansi16 = [16][3]uint8{
+	{0x00, 0x00, 0x00}, {0xCD, 0x00, 0x00}, {0x00, 0xCD, 0x00}, {0xCD, 0xCD, 0x00},
+	{0x00, 0x00, 0xEE}, {0xCD, 0x00, 0xCD}, {0x00, 0xCD, 0xCD}, {0xE5, 0xE5, 0xE5},
+	{0x7F, 0x7F, 0x7F}, {0xFF, 0x00, 0x00}, {0x00, 0xFF, 0x00}, {0xFF, 0xFF, 0x00},
+	{0x5C, 0x5C, 0xFF}, {0xFF, 0x00, 0xFF}, {0x00, 0xFF, 0xFF}, {0xFF, 0xFF, 0xFF},
+}
+
+// vgaPalette is the 16 colors of DOS text mode in ANSI order, which ANSI
+// art is drawn with
+var vgaPalette = [16][3]uint8{
+	{0x00, 0x00, 0x00}, {0xAA, 0x00, 0x00}, {0x00, 0xAA, 0x00}, {0xAA, 0x55, 0x00},
+	{0x00, 0x00, 0xAA}, {0xAA, 0x00, 0xAA}, {0x00, 0xAA, 0xAA}, {0xAA, 0xAA, 0xAA},
+	{0x55, 0x55, 0x55}, {0xFF, 0x55, 0x55}, {0x55, 0xFF, 0x55}, {0xFF, 0xFF, 0x55},
+	{0x55, 0x55, 0xFF}, {0xFF, 0x55, 0xFF}, {0x55, 0xFF, 0xFF}, {0xFF, 0xFF, 0xFF},
+}
+
+// cubeLevels are the channel values of the xterm 6x6x6 color cube
+var cubeLevels = [6]uint8{0x00, 0x5F, 0x87, 0xAF, 0xD7, 0xFF}
+
+// xterm256 returns the RGB value of an xterm-256 color index: the 16
+// basic colors, the 6x6x6 cube from 16 and the 24 grays from 232
+func xterm256(n uint8) (uint8, uint8, uint8) {
+	switch {
+	case n < 16:
+		c := ansi16[n]
+		return c[0], c[1], c[2]
+	case n < 232:
+		n -= 16
+		return cubeLevels[n/36], cubeLevels[n/6%6], cubeLevels[n%6]
+	default:
+		v := 8 + (n-232)*10
+		return v, v, v
+	}
+}
+
+// NearestXterm256 returns the xterm-256 color closest to r, g, b. Only the
+// cube and the grays are candidates: terminal themes redefine the first 16.
+func NearestXterm256(r, g, b uint8) uint8 {
+	best, bestDist := uint8(16), -1
+	for n := 16; n < 256; n++ {
+		cr, cg, cb := xterm256(uint8(n))
+		if d := colorDistance(r, g, b, cr, cg, cb); bestDist < 0 || d < bestDist {
+			best, bestDist = uint8(n), d
+		}
+	}
+	return best
+}
+
+// NearestANSI16 returns the index of the basic ANSI color closest to
+// r, g, b, 8-15 being the bright ones
+func NearestANSI16(r, g, b uint8) uint8 {
+	return nearest16(&ansi16, r, g, b)
+}
+
+func nearest16(palette *[16][3]uint8, r, g, b uint8) uint8 {
+	best, bestDist := uint8(0), -1
+	for n, c := range palette {
+		if d := colorDistance(r, g, b, c[0], c[1], c[2]); bestDist < 0 || d < bestDist {
+			best, bestDist = uint8(n), d
+		}
+	}
+	return best
+}
+
+// colorDistance is a squared RGB distance weighted by the mean red level,
+// a cheap approximation of perceived difference
+func colorDistance(r1, g1, b1, r2, g2, b2 uint8) int {
+	rmean := (int(r1) + int(r2)) / 2
+	dr, dg, db := int(r1)-int(r2), int(g1)-int(g2), int(b1)-int(b2)
+	return ((512+rmean)*dr*dr)>>8 + 4*dg*dg + ((767-rmean)*db*db)>>8
+}
+
+// QuantizeColor returns the color r, g, b is written as in mode: the
+// nearest palette color for 256 and 16, r, g, b itself otherwise
+func QuantizeColor(mode ColorMode, r, g, b uint8) (uint8, uint8, uint8) {
+	switch mode {
+	case Color256:
+		return xterm256(NearestXterm256(r, g, b))
+	case Color16:
+		c := ansi16[NearestANSI16(r, g, b)]
+		return c[0], c[1], c[2]
+	}
+	return r, g, b
+}
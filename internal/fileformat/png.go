@@ -0,0 +1,133 @@
+package fileformat
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SpriteAtlas describes the frames of a sprite sheet, written next to the
+// image as JSON
+type SpriteAtlas struct {
+	Image   string        `json:"image"`
+	Width   int           `json:"width"`  // sheet size in pixels
+	Height  int           `json:"height"` // sheet size in pixels
+	Columns int           `json:"columns"`
+	Cell    SpriteSize    `json:"cell"`   // pixels per character cell
+	Canvas  Canvas        `json:"canvas"` // frame size in cells
+	Frames  []SpriteFrame `json:"frames"`
+}
+
+// SpriteSize is a width and height in pixels
+type SpriteSize struct {
+	W int `json:"w"`
+	H int `json:"h"`
+}
+
+// SpriteFrame is the rectangle of one frame in the sheet
+type SpriteFrame struct {
+	Index    int    `json:"index"`
+	Name     string `json:"name,omitempty"`
+	X        int    `json:"x"`
+	Y        int    `json:"y"`
+	W        int    `json:"w"`
+	H        int    `json:"h"`
+	Duration int    `json:"duration"` // milliseconds
+}
+
+// exportPNG renders a single frame (the first unless one is selected)
+func exportPNG(aart *AartFile, path string, opts ExportOptions) error {
+	if len(aart.Frames) == 0 {
+		return fmt.Errorf("no frames to export")
+	}
+	frameIdx := 0
+	if opts.FrameIndex >= 0 && opts.FrameIndex < len(aart.Frames) {
+		frameIdx = opts.FrameIndex
+	}
+
+	r := NewRasterizer(opts.CellWidth, opts.CellHeight)
+	r.Monochrome = !opts.Colors
+	img := r.RenderCells(aart.Frames[frameIdx].Cells, aart.Canvas.Width, aart.Canvas.Height)
+	return writePNG(path, img)
+}
+
+// exportSpriteSheet lays the frames out in a grid in one PNG and writes
+// the atlas of frame rectangles and durations to the same path with a
+// .json extension
+func exportSpriteSheet(aart *AartFile, path string, opts ExportOptions) error {
+	start, end := frameRange(aart, opts)
+	count := end - start
+	if count <= 0 {
+		return fmt.Errorf("no frames to export")
+	}
+	if AtlasPath(path) == path {
+		return fmt.Errorf("sprite sheet path %s would be overwritten by its atlas", path)
+	}
+
+	columns := opts.Columns
+	if columns <= 0 {
+		columns = int(math.Ceil(math.Sqrt(float64(count))))
+	}
+	columns = min(columns, count)
+	rows := (count + columns - 1) / columns
+
+	r := NewRasterizer(opts.CellWidth, opts.CellHeight)
+	r.Monochrome = !opts.Colors
+	fw, fh := r.Size(aart.Canvas.Width, aart.Canvas.Height)
+	sheet := image.NewRGBA(image.Rect(0, 0, fw*columns, fh*rows))
+
+	atlas := SpriteAtlas{
+		Image:   filepath.Base(path),
+		Width:   fw * columns,
+		Height:  fh * rows,
+		Columns: columns,
+		Cell:    SpriteSize{W: r.CellWidth, H: r.CellHeight},
+		Canvas:  aart.Canvas,
+	}
+
+	for i := 0; i < count; i++ {
+		frame := &aart.Frames[start+i]
+		ox, oy := (i%columns)*fw, (i/columns)*fh
+		r.DrawCells(sheet, ox, oy, frame.Cells, aart.Canvas.Width, aart.Canvas.Height)
+		atlas.Frames = append(atlas.Frames, SpriteFrame{
+			Index:    start + i,
+			Name:     frame.Name,
+			X:        ox,
+			Y:        oy,
+			W:        fw,
+			H:        fh,
+			Duration: frame.Duration,
+		})
+	}
+
+	if err := writePNG(path, sheet); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(atlas, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(AtlasPath(path), data, 0644)
+}
+
+// AtlasPath returns where the atlas of a sprite sheet is written
+func AtlasPath(sheetPath string) string {
+	return strings.TrimSuffix(sheetPath, filepath.Ext(sheetPath)) + ".json"
+}
+
+func writePNG(path string, img image.Image) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(file, img); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,347 @@
+package fileformat
+
+import (
+	"image"
+	"image/color"
+	"unicode/utf8"
+)
+
+// Default cell size of image exports, in pixels. Twice the font's design
+// grid, so strokes stay even.
+const (
+	DefaultCellWidth  = 12
+	DefaultCellHeight = 20
+)
+
+// The font's design cell: the 5x8 glyph with one column of spacing and a
+// row of padding above and below
+const (
+	designWidth  = glyphWidth + 1
+	designHeight = glyphHeight + 2
+)
+
+// Rasterizer draws cells into images with the built-in font
+type Rasterizer struct {
+	CellWidth  int
+	CellHeight int
+	Monochrome bool // ignore cell colors, white on black
+
+	masks  map[glyphKey][]bool
+	colors map[string]color.RGBA
+}
+
+type glyphKey struct {
+	char                    rune
+	bold, italic, underline bool
+}
+
+var (
+	defaultFG = color.RGBA{0xFF, 0xFF, 0xFF, 0xFF}
+	defaultBG = color.RGBA{0x00, 0x00, 0x00, 0xFF}
+)
+
+// NewRasterizer returns a rasterizer for the given cell size; sizes below
+// 1 use the defaults
+func NewRasterizer(cellWidth, cellHeight int) *Rasterizer {
+	if cellWidth < 1 {
+		cellWidth = DefaultCellWidth
+	}
+	if cellHeight < 1 {
+		cellHeight = DefaultCellHeight
+	}
+	return &Rasterizer{
+		CellWidth:  cellWidth,
+		CellHeight: cellHeight,
+		masks:      make(map[glyphKey][]bool),
+		colors:     make(map[string]color.RGBA),
+	}
+}
+
+// Size returns the pixel size of a canvas of width x height cells
+func (r *Rasterizer) Size(width, height int) (int, int) {
+	return width * r.CellWidth, height * r.CellHeight
+}
+
+// RenderCells draws a grid of cells into a new image
+func (r *Rasterizer) RenderCells(cells [][]Cell, width, height int) *image.RGBA {
+	w, h := r.Size(width, height)
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	r.DrawCells(img, 0, 0, cells, width, height)
+	return img
+}
+
+// DrawCells draws a width x height grid of cells with its top-left corner
+// at px, py. Missing cells are drawn blank.
+func (r *Rasterizer) DrawCells(img *image.RGBA, px, py int, cells [][]Cell, width, height int) {
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			cell := blankCell
+			if y < len(cells) && x < len(cells[y]) {
+				cell = cells[y][x]
+			}
+			r.DrawCell(img, px+x*r.CellWidth, py+y*r.CellHeight, cell)
+		}
+	}
+}
+
+// DrawCell draws one cell with its top-left corner at px, py
+func (r *Rasterizer) DrawCell(img *image.RGBA, px, py int, cell Cell) {
+	fg, bg := r.color(cell.Foreground, defaultFG), r.color(cell.Background, defaultBG)
+	if r.Monochrome {
+		fg, bg = defaultFG, defaultBG
+	}
+	char, _ := utf8.DecodeRuneInString(cell.Char)
+	if cell.Char == "" {
+		char = ' '
+	}
+	mask := r.mask(glyphKey{char: char, bold: cell.Bold, italic: cell.Italic, underline: cell.Underline})
+
+	for y := 0; y < r.CellHeight; y++ {
+		row := img.Pix[img.PixOffset(px, py+y):]
+		for x := 0; x < r.CellWidth; x++ {
+			c := bg
+			if mask[y*r.CellWidth+x] {
+				c = fg
+			}
+			row[x*4], row[x*4+1], row[x*4+2], row[x*4+3] = c.R, c.G, c.B, c.A
+		}
+	}
+}
+
+func (r *Rasterizer) color(hex string, fallback color.RGBA) color.RGBA {
+	if c, ok := r.colors[hex]; ok {
+		return c
+	}
+	c := fallback
+	if red, green, blue, ok := ParseHexColor(hex); ok {
+		c = color.RGBA{red, green, blue, 0xFF}
+	}
+	r.colors[hex] = c
+	return c
+}
+
+// mask returns which pixels of a cell are drawn in the foreground color
+func (r *Rasterizer) mask(key glyphKey) []bool {
+	if m, ok := r.masks[key]; ok {
+		return m
+	}
+	cw, ch := r.CellWidth, r.CellHeight
+	m := make([]bool, cw*ch)
+
+	if !geometricMask(m, key.char, cw, ch) {
+		g, ok := compiledGlyphs[key.char]
+		if !ok {
+			g = missingGlyph
+		}
+		if key.italic {
+			// Shear: the top rows lean right
+			for y := range g {
+				g[y] <<= (glyphHeight - 1 - y) / 4
+			}
+		}
+		if key.bold {
+			for y := range g {
+				g[y] |= g[y] << 1
+			}
+		}
+		for py := 0; py < ch; py++ {
+			gy := py*designHeight/ch - 1
+			if gy < 0 || gy >= glyphHeight {
+				continue
+			}
+			for px := 0; px < cw; px++ {
+				gx := px * designWidth / cw
+				m[py*cw+px] = g[gy]&(1<<gx) != 0
+			}
+		}
+	}
+
+	if key.underline {
+		thickness := max(1, ch/16)
+		for py := ch - 1 - ch/20 - thickness + 1; py <= ch-1-ch/20; py++ {
+			for px := 0; px < cw; px++ {
+				m[py*cw+px] = true
+			}
+		}
+	}
+
+	r.masks[key] = m
+	return m
+}
+
+// missingGlyph is drawn for characters the font does not have
+var missingGlyph = glyphBitmap{0x1F, 0x11, 0x11, 0x11, 0x11, 0x11, 0x1F, 0x00}
+
+// geometricMask fills m for block elements, shades, quadrants, braille and
+// box drawing, which are drawn from their shape at the cell size. It
+// reports false for other characters.
+func geometricMask(m []bool, char rune, cw, ch int) bool {
+	fill := func(x0, y0, x1, y1 int) {
+		for y := max(y0, 0); y < min(y1, ch); y++ {
+			for x := max(x0, 0); x < min(x1, cw); x++ {
+				m[y*cw+x] = true
+			}
+		}
+	}
+
+	switch {
+	case char == '█':
+		fill(0, 0, cw, ch)
+	case char == '▀':
+		fill(0, 0, cw, ch/2)
+	case char >= '▁' && char <= '▇': // lower eighths
+		n := int(char-'▁') + 1
+		fill(0, ch-ch*n/8, cw, ch)
+	case char >= '▉' && char <= '▏': // left eighths, 7/8 down to 1/8
+		n := 7 - int(char-'▉')
+		fill(0, 0, cw*n/8, ch)
+	case char == '▐':
+		fill(cw/2, 0, cw, ch)
+	case char == '▔':
+		fill(0, 0, cw, max(1, ch/8))
+	case char == '▕':
+		fill(cw-max(1, cw/8), 0, cw, ch)
+	case char >= '░' && char <= '▓':
+		level := int(char - '░') // 0: 25%, 1: 50%, 2: 75%
+		for y := 0; y < ch; y++ {
+			for x := 0; x < cw; x++ {
+				switch level {
+				case 0:
+					m[y*cw+x] = x%2 == 0 && y%2 == 0
+				case 1:
+					m[y*cw+x] = (x+y)%2 == 0
+				default:
+					m[y*cw+x] = x%2 == 0 || y%2 == 0
+				}
+			}
+		}
+	case char >= '▖' && char <= '▟':
+		quadrantMask(fill, quadrants[char-'▖'], cw, ch)
+	case char >= 0x2800 && char <= 0x28FF:
+		brailleMask(fill, uint8(char-0x2800), cw, ch)
+	default:
+		arms, ok := boxGlyphs[char]
+		if !ok {
+			return false
+		}
+		boxMask(fill, arms, cw, ch)
+	}
+	return true
+}
+
+// Quadrant bits: upper left, upper right, lower left, lower right
+const (
+	quadUL = 1 << iota
+	quadUR
+	quadLL
+	quadLR
+)
+
+// quadrants maps U+2596..U+259F to the quadrants they fill
+var quadrants = [10]uint8{
+	quadLL,                   // ▖
+	quadLR,                   // ▗
+	quadUL,                   // ▘
+	quadUL | quadLL | quadLR, // ▙
+	quadUL | quadLR,          // ▚
+	quadUL | quadUR | quadLL, // ▛
+	quadUL | quadUR | quadLR, // ▜
+	quadUR,                   // ▝
+	quadUR | quadLL,          // ▞
+	quadUR | quadLL | quadLR, // ▟
+}
+
+func quadrantMask(fill func(x0, y0, x1, y1 int), q uint8, cw, ch int) {
+	hx, hy := cw/2, ch/2
+	if q&quadUL != 0 {
+		fill(0, 0, hx, hy)
+	}
+	if q&quadUR != 0 {
+		fill(hx, 0, cw, hy)
+	}
+	if q&quadLL != 0 {
+		fill(0, hy, hx, ch)
+	}
+	if q&quadLR != 0 {
+		fill(hx, hy, cw, ch)
+	}
+}
+
+// brailleDots gives the column and row of each braille dot bit
+var brailleDots = [8][2]int{{0, 0}, {0, 1}, {0, 2}, {1, 0}, {1, 1}, {1, 2}, {0, 3}, {1, 3}}
+
+func brailleMask(fill func(x0, y0, x1, y1 int), bits uint8, cw, ch int) {
+	size := max(1, min(cw/2, ch/4)*2/3)
+	for i, pos := range brailleDots {
+		if bits&(1<<i) == 0 {
+			continue
+		}
+		cx := pos[0]*cw/2 + cw/4
+		cy := pos[1]*ch/4 + ch/8
+		fill(cx-size/2, cy-size/2, cx-size/2+size, cy-size/2+size)
+	}
+}
+
+func boxMask(fill func(x0, y0, x1, y1 int), arms [4]uint8, cw, ch int) {
+	light := max(1, cw/8)
+	gap := max(1, cw/6) // half the distance between double lines
+
+	thickness := func(weight uint8) int {
+		if weight == armHeavy {
+			return light * 2
+		}
+		return light
+	}
+	offsets := func(weight uint8) []int {
+		if weight == armDouble {
+			return []int{-gap, gap}
+		}
+		return []int{0}
+	}
+	// span is the extent across a stroke of the given weight on axis c
+	span := func(c int, weights ...uint8) (int, int) {
+		lo, hi := c, c
+		for _, w := range weights {
+			if w == armNone {
+				continue
+			}
+			t := thickness(w)
+			for _, off := range offsets(w) {
+				lo, hi = min(lo, c+off-t/2), max(hi, c+off-t/2+t)
+			}
+		}
+		return lo, hi
+	}
+
+	cx, cy := cw/2, ch/2
+	up, right, down, left := arms[0], arms[1], arms[2], arms[3]
+
+	// Vertical arms run to the far edge of the horizontal strokes so that
+	// corners and joins are closed, and the other way around
+	ylo, yhi := span(cy, left, right, max(up, down))
+	xlo, xhi := span(cx, up, down, max(left, right))
+	for _, off := range offsets(up) {
+		x := cx + off - thickness(up)/2
+		if up != armNone {
+			fill(x, 0, x+thickness(up), yhi)
+		}
+	}
+	for _, off := range offsets(down) {
+		x := cx + off - thickness(down)/2
+		if down != armNone {
+			fill(x, ylo, x+thickness(down), ch)
+		}
+	}
+	for _, off := range offsets(left) {
+		y := cy + off - thickness(left)/2
+		if left != armNone {
+			fill(0, y, xhi, y+thickness(left))
+		}
+	}
+	for _, off := range offsets(right) {
+		y := cy + off - thickness(right)/2
+		if right != armNone {
+			fill(xlo, y, cw, y+thickness(right))
+		}
+	}
+}
@@ -0,0 +1,238 @@
+package fileformat
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Schema is the JSON Schema of the current .aart format
+//
+//go:embed aart.schema.json
+var Schema []byte
+
+// maxSchemaErrors stops validation of badly broken files early
+const maxSchemaErrors = 100
+
+// SchemaError is a schema violation at a JSON pointer into the document
+type SchemaError struct {
+	Path    string
+	Message string
+}
+
+func (e SchemaError) Error() string {
+	path := e.Path
+	if path == "" {
+		path = "/"
+	}
+	return fmt.Sprintf("%s: %s", path, e.Message)
+}
+
+var (
+	schemaOnce sync.Once
+	schemaRoot map[string]any
+	schemaErr  error
+	patterns   sync.Map // pattern string -> *regexp.Regexp
+)
+
+func loadSchema() (map[string]any, error) {
+	schemaOnce.Do(func() {
+		schemaErr = json.Unmarshal(Schema, &schemaRoot)
+	})
+	return schemaRoot, schemaErr
+}
+
+// ValidateSchema checks a raw JSON .aart document against Schema. Only the
+// keywords the schema uses are supported: $ref, type, enum, pattern,
+// minimum, maximum, required, properties, additionalProperties and items.
+func ValidateSchema(data []byte) ([]SchemaError, error) {
+	var doc any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	return ValidateDocument(doc)
+}
+
+// ValidateDocument checks an already decoded JSON document against Schema
+func ValidateDocument(doc any) ([]SchemaError, error) {
+	root, err := loadSchema()
+	if err != nil {
+		return nil, fmt.Errorf("embedded schema: %w", err)
+	}
+	v := schemaValidator{root: root}
+	v.check(root, doc, "")
+	return v.errs, nil
+}
+
+type schemaValidator struct {
+	root map[string]any
+	errs []SchemaError
+}
+
+func (v *schemaValidator) fail(path, format string, args ...any) {
+	if len(v.errs) < maxSchemaErrors {
+		v.errs = append(v.errs, SchemaError{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+}
+
+func (v *schemaValidator) resolve(ref string) map[string]any {
+	name, ok := strings.CutPrefix(ref, "#/$defs/")
+	if !ok {
+		return nil
+	}
+	defs, _ := v.root["$defs"].(map[string]any)
+	def, _ := defs[name].(map[string]any)
+	return def
+}
+
+func (v *schemaValidator) check(schema map[string]any, value any, path string) {
+	if len(v.errs) >= maxSchemaErrors {
+		return
+	}
+	if ref, ok := schema["$ref"].(string); ok {
+		def := v.resolve(ref)
+		if def == nil {
+			v.fail(path, "unresolved schema reference %s", ref)
+			return
+		}
+		v.check(def, value, path)
+		return
+	}
+
+	if t, ok := schema["type"]; ok && !matchesType(t, value) {
+		v.fail(path, "expected %s, got %s", typeNames(t), jsonType(value))
+		return
+	}
+
+	if enum, ok := schema["enum"].([]any); ok {
+		found := false
+		for _, e := range enum {
+			if e == value {
+				found = true
+				break
+			}
+		}
+		if !found {
+			v.fail(path, "%v is not one of %v", value, enum)
+		}
+	}
+
+	switch value := value.(type) {
+	case string:
+		if p, ok := schema["pattern"].(string); ok && !compilePattern(p).MatchString(value) {
+			v.fail(path, "%q does not match %s", value, p)
+		}
+	case float64:
+		if minimum, ok := schema["minimum"].(float64); ok && value < minimum {
+			v.fail(path, "%v is less than %v", value, minimum)
+		}
+		if maximum, ok := schema["maximum"].(float64); ok && value > maximum {
+			v.fail(path, "%v is greater than %v", value, maximum)
+		}
+	case map[string]any:
+		v.checkObject(schema, value, path)
+	case []any:
+		if items, ok := schema["items"].(map[string]any); ok {
+			for i, item := range value {
+				v.check(items, item, path+"/"+strconv.Itoa(i))
+			}
+		}
+	}
+}
+
+func (v *schemaValidator) checkObject(schema map[string]any, obj map[string]any, path string) {
+	if required, ok := schema["required"].([]any); ok {
+		for _, r := range required {
+			if name, _ := r.(string); name != "" {
+				if _, ok := obj[name]; !ok {
+					v.fail(path, "missing required property %q", name)
+				}
+			}
+		}
+	}
+
+	props, _ := schema["properties"].(map[string]any)
+	additional, hasAdditional := schema["additionalProperties"].(bool)
+
+	// Sorted so reports are stable
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		if sub, ok := props[k].(map[string]any); ok {
+			v.check(sub, obj[k], path+"/"+k)
+		} else if hasAdditional && !additional {
+			v.fail(path, "unknown property %q", k)
+		}
+	}
+}
+
+func compilePattern(p string) *regexp.Regexp {
+	if re, ok := patterns.Load(p); ok {
+		return re.(*regexp.Regexp)
+	}
+	re := regexp.MustCompile(p)
+	patterns.Store(p, re)
+	return re
+}
+
+// jsonType names the JSON type of a decoded value
+func jsonType(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if v == math.Trunc(v) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// matchesType checks a value against a "type" keyword, a name or a list
+func matchesType(t any, value any) bool {
+	actual := jsonType(value)
+	match := func(name string) bool {
+		return name == actual || (name == "number" && actual == "integer")
+	}
+	switch t := t.(type) {
+	case string:
+		return match(t)
+	case []any:
+		for _, name := range t {
+			if s, ok := name.(string); ok && match(s) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func typeNames(t any) string {
+	if list, ok := t.([]any); ok {
+		names := make([]string, len(list))
+		for i, n := range list {
+			names[i] = fmt.Sprint(n)
+		}
+		return strings.Join(names, " or ")
+	}
+	return fmt.Sprint(t)
+}
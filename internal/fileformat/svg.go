@@ -0,0 +1,201 @@
+package fileformat
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// svgStyle is what a <tspan> run of characters shares
+type svgStyle struct {
+	fill                    string
+	bold, italic, underline bool
+}
+
+func (s svgStyle) attrs() string {
+	var b strings.Builder
+	if s.fill != "" {
+		b.WriteString(fmt.Sprintf(" fill=\"%s\"", escapeHTML(s.fill)))
+	}
+	if s.bold {
+		b.WriteString(" font-weight=\"bold\"")
+	}
+	if s.italic {
+		b.WriteString(" font-style=\"italic\"")
+	}
+	if s.underline {
+		b.WriteString(" text-decoration=\"underline\"")
+	}
+	return b.String()
+}
+
+// exportSVG writes the frames as an SVG. A single frame is drawn as is;
+// several are stacked as groups whose visibility is cycled by a CSS
+// animation with the frame durations, so the file plays without scripts.
+func exportSVG(aart *AartFile, path string, opts ExportOptions) error {
+	start, end := frameRange(aart, opts)
+	if start >= end {
+		return fmt.Errorf("no frames to export")
+	}
+
+	cw, ch := opts.CellWidth, opts.CellHeight
+	if cw < 1 {
+		cw = DefaultCellWidth
+	}
+	if ch < 1 {
+		ch = DefaultCellHeight
+	}
+	// Monospace advances are about 0.6em; every run is also stretched to
+	// its exact width with textLength, so the font only needs to fit
+	fontSize := min(float64(cw)/0.6, float64(ch)*0.8)
+	width, height := aart.Canvas.Width*cw, aart.Canvas.Height*ch
+
+	// Identical frames share one group, shown in each of their windows
+	var groups []string
+	var windows [][][2]int // per group, start and end times in ms
+	index := map[string]int{}
+	total := 0
+	for i := start; i < end; i++ {
+		body := svgFrame(&aart.Frames[i], aart.Canvas, cw, ch, fontSize, opts.Colors)
+		g, ok := index[body]
+		if !ok {
+			g = len(groups)
+			index[body] = g
+			groups = append(groups, body)
+			windows = append(windows, nil)
+		}
+		d := max(aart.Frames[i].Duration, MinDuration)
+		if w := windows[g]; len(w) > 0 && w[len(w)-1][1] == total {
+			w[len(w)-1][1] += d
+		} else {
+			windows[g] = append(w, [2]int{total, total + d})
+		}
+		total += d
+	}
+	animated := len(groups) > 1
+
+	var svg strings.Builder
+	svg.WriteString(fmt.Sprintf("<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%d\" height=\"%d\" viewBox=\"0 0 %d %d\" xml:space=\"preserve\">\n",
+		width, height, width, height))
+	svg.WriteString(fmt.Sprintf("<title>%s</title>\n", escapeHTML(aart.Metadata.Title)))
+	svg.WriteString("<style>\n")
+	svg.WriteString(fmt.Sprintf("text { font-family: monospace; font-size: %spx; white-space: pre; fill: #FFFFFF; }\n", svgNumber(fontSize)))
+	if animated {
+		svg.WriteString(fmt.Sprintf(".f { visibility: hidden; animation: %dms step-end infinite; }\n", total))
+		for g, w := range windows {
+			svg.WriteString(fmt.Sprintf("#f%d { animation-name: k%d; }\n", g, g))
+			svg.WriteString(fmt.Sprintf("@keyframes k%d {", g))
+			for _, win := range w {
+				svg.WriteString(fmt.Sprintf(" %s%% { visibility: visible; }", svgPercent(win[0], total)))
+				if win[1] < total {
+					svg.WriteString(fmt.Sprintf(" %s%% { visibility: hidden; }", svgPercent(win[1], total)))
+				}
+			}
+			svg.WriteString(" }\n")
+		}
+	}
+	svg.WriteString("</style>\n")
+	svg.WriteString(fmt.Sprintf("<rect width=\"%d\" height=\"%d\" fill=\"#000000\"/>\n", width, height))
+
+	for g, body := range groups {
+		if animated {
+			svg.WriteString(fmt.Sprintf("<g id=\"f%d\" class=\"f\">\n", g))
+		} else {
+			svg.WriteString("<g>\n")
+		}
+		svg.WriteString(body)
+		svg.WriteString("</g>\n")
+	}
+	svg.WriteString("</svg>\n")
+
+	return os.WriteFile(path, []byte(svg.String()), 0644)
+}
+
+// svgFrame returns the background rectangles and text of one frame. Cells
+// sharing a background become one <rect> and cells sharing a style one
+// <tspan>, per row.
+func svgFrame(frame *Frame, canvas Canvas, cw, ch int, fontSize float64, colors bool) string {
+	var b strings.Builder
+	// Center the cap height in the cell
+	baseline := (float64(ch) + fontSize*0.7) / 2
+
+	for y := 0; y < canvas.Height && y < len(frame.Cells); y++ {
+		row := frame.Cells[y]
+		n := min(canvas.Width, len(row))
+		top := y * ch
+
+		if colors {
+			for x := 0; x < n; {
+				bg := row[x].Background
+				run := 1
+				for x+run < n && row[x+run].Background == bg {
+					run++
+				}
+				if bg != "" && !strings.EqualFold(bg, "#000000") {
+					b.WriteString(fmt.Sprintf("<rect x=\"%d\" y=\"%d\" width=\"%d\" height=\"%d\" fill=\"%s\"/>\n",
+						x*cw, top, run*cw, ch, escapeHTML(bg)))
+				}
+				x += run
+			}
+		}
+
+		var text strings.Builder
+		for x := 0; x < n; {
+			style := svgCellStyle(row[x], colors)
+			run := 1
+			for x+run < n && svgCellStyle(row[x+run], colors) == style {
+				run++
+			}
+			// Leading and trailing blanks only need their background
+			lo, hi := x, x+run
+			for lo < hi && svgBlank(row[lo]) {
+				lo++
+			}
+			for hi > lo && svgBlank(row[hi-1]) {
+				hi--
+			}
+			if lo < hi {
+				var chars strings.Builder
+				for _, cell := range row[lo:hi] {
+					if cell.Char == "" {
+						chars.WriteByte(' ')
+					} else {
+						chars.WriteString(cell.Char)
+					}
+				}
+				text.WriteString(fmt.Sprintf("<tspan x=\"%d\" textLength=\"%d\" lengthAdjust=\"spacingAndGlyphs\"%s>%s</tspan>",
+					lo*cw, (hi-lo)*cw, style.attrs(), escapeHTML(chars.String())))
+			}
+			x += run
+		}
+		if text.Len() > 0 {
+			b.WriteString(fmt.Sprintf("<text y=\"%s\">%s</text>\n", svgNumber(float64(top)+baseline), text.String()))
+		}
+	}
+	return b.String()
+}
+
+func svgCellStyle(cell Cell, colors bool) svgStyle {
+	s := svgStyle{bold: cell.Bold, italic: cell.Italic, underline: cell.Underline}
+	if colors && !strings.EqualFold(cell.Foreground, "#FFFFFF") {
+		s.fill = cell.Foreground
+	}
+	return s
+}
+
+// svgBlank reports whether a cell draws nothing over its background
+func svgBlank(cell Cell) bool {
+	return (cell.Char == "" || cell.Char == " ") && !cell.Underline
+}
+
+// svgPercent formats a time as a percentage of the loop
+func svgPercent(ms, total int) string {
+	return svgNumber(float64(ms) * 100 / float64(total))
+}
+
+// svgNumber formats f with at most three decimals
+func svgNumber(f float64) string {
+	return strconv.FormatFloat(math.Round(f*1000)/1000, 'f', -1, 64)
+}
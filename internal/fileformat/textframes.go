@@ -0,0 +1,183 @@
+package fileformat
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// DefaultFrameDelimiter separates the frames of a text export, as in
+// fortune files
+const DefaultFrameDelimiter = "%"
+
+// exportTextFrames writes every frame as plain text, one line per row,
+// with a delimiter line between frames. With Durations, every frame
+// starts with a header instead: the delimiter, a space and the duration,
+// such as "% 120ms".
+func exportTextFrames(aart *AartFile, path string, opts ExportOptions) error {
+	start, end := frameRange(aart, opts)
+	if start >= end {
+		return fmt.Errorf("no frames to export")
+	}
+	delim := opts.Delimiter
+	if delim == "" {
+		delim = DefaultFrameDelimiter
+	}
+	if strings.ContainsAny(delim, "\n\f") {
+		return fmt.Errorf("frame delimiter must be a single line")
+	}
+	separator := textSeparator(delim)
+
+	var out strings.Builder
+	for i := start; i < end; i++ {
+		frame := &aart.Frames[i]
+		switch {
+		case opts.Durations:
+			out.WriteString(fmt.Sprintf("%s %dms\n", delim, frame.Duration))
+		case i > start:
+			out.WriteString(delim + "\n")
+		}
+		for y := 0; y < aart.Canvas.Height; y++ {
+			var row strings.Builder
+			for x := 0; x < aart.Canvas.Width; x++ {
+				c := cellAt(frame.Cells, x, y)
+				if c.Char == "" || strings.ContainsAny(c.Char, "\n\r\f\t") {
+					row.WriteByte(' ')
+				} else {
+					row.WriteString(c.Char)
+				}
+			}
+			if separator.MatchString(row.String()) {
+				return fmt.Errorf("frame %d row %d reads as the delimiter %q, choose another one", i, y, delim)
+			}
+			out.WriteString(row.String() + "\n")
+		}
+	}
+	return os.WriteFile(path, []byte(out.String()), 0644)
+}
+
+// textSeparator matches a delimiter line, with an optional duration
+func textSeparator(delim string) *regexp.Regexp {
+	return regexp.MustCompile(`^` + regexp.QuoteMeta(delim) + `(?:[ \t]+(\d+)(?:ms)?)?[ \t]*$`)
+}
+
+// TextImportOptions controls how text is split into frames
+type TextImportOptions struct {
+	Delimiter string // line between frames ("" = DefaultFrameDelimiter)
+	Duration  int    // ms of frames without a duration header (0 = DefaultDuration)
+}
+
+// ImportText reads frames from plain text: the output of the frames
+// export, or any text with frames separated by delimiter lines or form
+// feeds. A delimiter line may carry the duration of the frame after it.
+// The canvas is as wide as the longest line and as tall as the tallest
+// frame; tabs expand to 8 columns.
+func ImportText(path string, opts TextImportOptions) (*AartFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	delim := opts.Delimiter
+	if delim == "" {
+		delim = DefaultFrameDelimiter
+	}
+	separator := textSeparator(delim)
+	duration := opts.Duration
+	if duration <= 0 {
+		duration = DefaultDuration
+	}
+
+	type textFrame struct {
+		lines    []string
+		duration int
+	}
+	var frames []textFrame
+	cur := textFrame{duration: duration}
+	started := false // whether cur has content or a header
+	flush := func() {
+		if started {
+			frames = append(frames, cur)
+		}
+		cur, started = textFrame{duration: duration}, false
+	}
+
+	text := strings.ReplaceAll(string(data), "\r\n", "\n")
+	for i, page := range strings.Split(text, "\f") {
+		if i > 0 {
+			flush()
+			// A form feed usually sits on a line of its own
+			page = strings.TrimPrefix(page, "\n")
+		}
+		page = strings.TrimSuffix(page, "\n")
+		if page == "" {
+			continue
+		}
+		for _, line := range strings.Split(page, "\n") {
+			if m := separator.FindStringSubmatch(line); m != nil {
+				flush()
+				if m[1] != "" {
+					d, _ := strconv.Atoi(m[1])
+					cur.duration, started = d, true
+				}
+				continue
+			}
+			cur.lines = append(cur.lines, expandTabs(strings.TrimSuffix(line, "\r")))
+			started = true
+		}
+	}
+	flush()
+	if len(frames) == 0 {
+		return nil, fmt.Errorf("%s: no frames", path)
+	}
+
+	width, height := 1, 1
+	for _, f := range frames {
+		height = max(height, len(f.lines))
+		for _, line := range f.lines {
+			width = max(width, len([]rune(line)))
+		}
+	}
+
+	aart := NewAartFile(width, height, strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)))
+	aart.Metadata.Source = path
+	aart.Layers = nil
+	for _, f := range frames {
+		cells := make([][]Cell, height)
+		for y := range cells {
+			cells[y] = make([]Cell, width)
+			for x := range cells[y] {
+				cells[y][x] = blankCell
+			}
+			if y < len(f.lines) {
+				for x, r := range []rune(f.lines[y]) {
+					cells[y][x].Char = string(r)
+				}
+			}
+		}
+		aart.AddFrame(cells, min(max(f.duration, MinDuration), MaxDuration))
+	}
+	return aart, nil
+}
+
+// expandTabs replaces tabs with spaces up to the next multiple of 8
+func expandTabs(line string) string {
+	if !strings.Contains(line, "\t") {
+		return line
+	}
+	var b strings.Builder
+	col := 0
+	for _, r := range line {
+		if r == '\t' {
+			n := 8 - col%8
+			b.WriteString(strings.Repeat(" ", n))
+			col += n
+			continue
+		}
+		b.WriteRune(r)
+		col++
+	}
+	return b.String()
+}
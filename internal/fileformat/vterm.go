@@ -0,0 +1,615 @@
+package fileformat
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// terminal is a small VT100/xterm screen emulator. It understands the
+// cursor movement, erasing, scrolling and SGR sequences that recorded
+// terminal sessions and ANSI art use, which is enough to rebuild what was
+// on screen at any point. Unknown sequences are parsed and ignored.
+type terminal struct {
+	width, height int
+	cells         [][]Cell
+
+	x, y        int
+	wrapPending bool // the last column was written; wrap on the next print
+	autowrap    bool
+	top, bottom int // scroll region, inclusive rows
+	pen         pen
+	saved       savedCursor
+
+	alt      [][]Cell // the main screen while the alternate one is shown
+	graphics bool     // G0 is the DEC special graphics set
+	charset  rune     // which set ESC ( ) * + is designating
+
+	// ANSI art conventions (see newArtTerminal)
+	art       bool
+	iceColors bool
+
+	state   parserState
+	params  []byte
+	partial []byte // an incomplete UTF-8 sequence at the end of a write
+}
+
+// pen is the current drawing style. Colors are either one of the 16
+// basic colors (fgIndex, bgIndex) or a hex color; -1 and "" are the
+// defaults.
+type pen struct {
+	fg, bg                  string
+	fgIndex, bgIndex        int
+	bold, italic, underline bool
+	blink, inverse          bool
+}
+
+var defaultPen = pen{fgIndex: -1, bgIndex: -1}
+
+type savedCursor struct {
+	x, y int
+	pen  pen
+}
+
+type parserState int
+
+const (
+	stateGround parserState = iota
+	stateEscape
+	stateCharset // ESC ( and friends, waiting for the set
+	stateCSI
+	stateString       // OSC, DCS, APC, PM and SOS, up to BEL or ST
+	stateStringEscape // ESC inside a string, possibly the start of ST
+)
+
+func newTerminal(width, height int) *terminal {
+	t := &terminal{width: width, height: height, autowrap: true, bottom: height - 1, pen: defaultPen}
+	t.saved.pen = defaultPen
+	t.cells = t.blankScreen()
+	return t
+}
+
+// newArtTerminal returns a terminal that draws like DOS ANSI art viewers:
+// the VGA palette, light gray on black by default, and bold selecting the
+// bright foreground colors instead of a bold font. With iceColors, blink
+// selects the bright background colors instead of blinking.
+func newArtTerminal(width, height int, iceColors bool) *terminal {
+	t := newTerminal(width, height)
+	t.art, t.iceColors = true, iceColors
+	return t
+}
+
+// Write feeds output to the terminal. It never fails.
+func (t *terminal) Write(p []byte) (int, error) {
+	data := p
+	if len(t.partial) > 0 {
+		data = append(t.partial, p...)
+		t.partial = nil
+	}
+	for i := 0; i < len(data); {
+		b := data[i]
+		if b < utf8.RuneSelf || t.state != stateGround {
+			t.feed(rune(b))
+			i++
+			continue
+		}
+		if !utf8.FullRune(data[i:]) {
+			t.partial = append([]byte(nil), data[i:]...)
+			break
+		}
+		r, size := utf8.DecodeRune(data[i:])
+		t.feed(r)
+		i += size
+	}
+	return len(p), nil
+}
+
+// Screen returns a copy of the visible cells
+func (t *terminal) Screen() [][]Cell {
+	out := make([][]Cell, t.height)
+	for y := range out {
+		out[y] = append([]Cell(nil), t.cells[y]...)
+	}
+	return out
+}
+
+func (t *terminal) feed(r rune) {
+	switch t.state {
+	case stateEscape:
+		t.escape(r)
+		return
+	case stateCharset:
+		if t.charset == '(' {
+			t.graphics = r == '0'
+		}
+		t.state = stateGround
+		return
+	case stateCSI:
+		if r >= 0x40 && r <= 0x7E {
+			t.csi(r)
+			t.state = stateGround
+		} else if r >= 0x20 {
+			t.params = append(t.params, byte(r))
+		} else {
+			t.control(r) // controls are executed inside sequences
+		}
+		return
+	case stateString:
+		if r == 0x07 {
+			t.state = stateGround
+		} else if r == 0x1B {
+			t.state = stateStringEscape
+		}
+		return
+	case stateStringEscape:
+		if r == '\\' {
+			t.state = stateGround
+		} else {
+			t.state = stateString
+		}
+		return
+	}
+
+	if r < 0x20 || r == 0x7F {
+		t.control(r)
+		return
+	}
+	t.print(r)
+}
+
+func (t *terminal) control(r rune) {
+	switch r {
+	case 0x1B:
+		t.state = stateEscape
+	case '\b':
+		t.moveTo(t.x-1, t.y)
+	case '\t':
+		t.moveTo(min((t.x/8+1)*8, t.width-1), t.y)
+	case '\n', '\v', '\f':
+		t.lineFeed()
+	case '\r':
+		t.moveTo(0, t.y)
+	}
+}
+
+func (t *terminal) escape(r rune) {
+	t.state = stateGround
+	switch r {
+	case '[':
+		t.state = stateCSI
+		t.params = t.params[:0]
+	case ']', 'P', '_', '^', 'X':
+		t.state = stateString
+	case '(', ')', '*', '+':
+		t.state = stateCharset
+		t.charset = r
+	case '7':
+		t.saved = savedCursor{x: t.x, y: t.y, pen: t.pen}
+	case '8':
+		t.pen = t.saved.pen
+		t.moveTo(t.saved.x, t.saved.y)
+	case 'D':
+		t.lineFeed()
+	case 'E':
+		t.moveTo(0, t.y)
+		t.lineFeed()
+	case 'M':
+		if t.y == t.top {
+			t.scrollDown(1)
+		} else {
+			t.moveTo(t.x, t.y-1)
+		}
+	case 'c':
+		*t = *newTerminal(t.width, t.height)
+	}
+}
+
+func (t *terminal) csi(final rune) {
+	private := len(t.params) > 0 && (t.params[0] == '?' || t.params[0] == '>' || t.params[0] == '=')
+	args := t.args()
+	arg := func(i, def int) int {
+		if i < len(args) && args[i] > 0 {
+			return args[i]
+		}
+		return def
+	}
+
+	if private {
+		if final == 'h' || final == 'l' {
+			for _, mode := range args {
+				t.privateMode(mode, final == 'h')
+			}
+		}
+		return
+	}
+
+	switch final {
+	case '@':
+		t.insertChars(arg(0, 1))
+	case 'A':
+		// Stop at the scroll region when starting inside it
+		limit := 0
+		if t.y >= t.top {
+			limit = t.top
+		}
+		t.moveTo(t.x, max(t.y-arg(0, 1), limit))
+	case 'B', 'e':
+		limit := t.height - 1
+		if t.y <= t.bottom {
+			limit = t.bottom
+		}
+		t.moveTo(t.x, min(t.y+arg(0, 1), limit))
+	case 'C', 'a':
+		t.moveTo(t.x+arg(0, 1), t.y)
+	case 'D':
+		t.moveTo(t.x-arg(0, 1), t.y)
+	case 'E':
+		t.moveTo(0, t.y+arg(0, 1))
+	case 'F':
+		t.moveTo(0, t.y-arg(0, 1))
+	case 'G', '`':
+		t.moveTo(arg(0, 1)-1, t.y)
+	case 'H', 'f':
+		t.moveTo(arg(1, 1)-1, arg(0, 1)-1)
+	case 'd':
+		t.moveTo(t.x, arg(0, 1)-1)
+	case 'J':
+		t.eraseDisplay(arg(0, 0))
+	case 'K':
+		t.eraseLine(arg(0, 0))
+	case 'L':
+		if t.y >= t.top && t.y <= t.bottom {
+			t.scrollRegion(t.y, t.bottom, -arg(0, 1))
+		}
+	case 'M':
+		if t.y >= t.top && t.y <= t.bottom {
+			t.scrollRegion(t.y, t.bottom, arg(0, 1))
+		}
+	case 'P':
+		t.deleteChars(arg(0, 1))
+	case 'S':
+		t.scrollUp(arg(0, 1))
+	case 'T':
+		t.scrollDown(arg(0, 1))
+	case 'X':
+		for x := t.x; x < min(t.x+arg(0, 1), t.width); x++ {
+			t.cells[t.y][x] = t.blank()
+		}
+	case 'm':
+		t.sgr(args)
+	case 'r':
+		top, bottom := arg(0, 1)-1, arg(1, t.height)-1
+		if top < bottom && bottom < t.height {
+			t.top, t.bottom = top, bottom
+			t.moveTo(0, 0)
+		}
+	case 's':
+		t.saved = savedCursor{x: t.x, y: t.y, pen: t.pen}
+	case 'u':
+		t.moveTo(t.saved.x, t.saved.y)
+	}
+}
+
+// args parses the numeric parameters; sub-parameters separated by colons
+// are flattened, and missing ones are 0
+func (t *terminal) args() []int {
+	var args []int
+	n, has := 0, false
+	for _, b := range t.params {
+		switch {
+		case b >= '0' && b <= '9':
+			n, has = n*10+int(b-'0'), true
+		case b == ';' || b == ':':
+			args = append(args, n)
+			n, has = 0, false
+		}
+	}
+	if has || len(args) > 0 {
+		args = append(args, n)
+	}
+	return args
+}
+
+func (t *terminal) privateMode(mode int, set bool) {
+	switch mode {
+	case 7:
+		t.autowrap = set
+	case 47, 1047, 1049:
+		if set && t.alt == nil {
+			if mode == 1049 {
+				t.saved = savedCursor{x: t.x, y: t.y, pen: t.pen}
+			}
+			t.alt, t.cells = t.cells, t.blankScreen()
+		} else if !set && t.alt != nil {
+			t.cells, t.alt = t.alt, nil
+			if mode == 1049 {
+				t.pen = t.saved.pen
+				t.moveTo(t.saved.x, t.saved.y)
+			}
+		}
+	}
+}
+
+func (t *terminal) sgr(args []int) {
+	if len(args) == 0 {
+		args = []int{0}
+	}
+	for i := 0; i < len(args); i++ {
+		switch a := args[i]; {
+		case a == 0:
+			t.pen = defaultPen
+		case a == 1:
+			t.pen.bold = true
+		case a == 3:
+			t.pen.italic = true
+		case a == 4:
+			t.pen.underline = true
+		case a == 5:
+			t.pen.blink = true
+		case a == 7:
+			t.pen.inverse = true
+		case a == 22:
+			t.pen.bold = false
+		case a == 23:
+			t.pen.italic = false
+		case a == 24:
+			t.pen.underline = false
+		case a == 25:
+			t.pen.blink = false
+		case a == 27:
+			t.pen.inverse = false
+		case a >= 30 && a <= 37:
+			t.pen.fg, t.pen.fgIndex = "", a-30
+		case a >= 40 && a <= 47:
+			t.pen.bg, t.pen.bgIndex = "", a-40
+		case a >= 90 && a <= 97:
+			t.pen.fg, t.pen.fgIndex = "", a-90+8
+		case a >= 100 && a <= 107:
+			t.pen.bg, t.pen.bgIndex = "", a-100+8
+		case a == 39:
+			t.pen.fg, t.pen.fgIndex = "", -1
+		case a == 49:
+			t.pen.bg, t.pen.bgIndex = "", -1
+		case a == 38 || a == 48:
+			var c string
+			c, i = t.extendedColor(args, i)
+			if a == 38 {
+				t.pen.fg, t.pen.fgIndex = c, -1
+			} else {
+				t.pen.bg, t.pen.bgIndex = c, -1
+			}
+		}
+	}
+}
+
+// extendedColor parses 38/48 ;5;n or ;2;r;g;b starting at args[i] and
+// returns the color and the index of its last parameter
+func (t *terminal) extendedColor(args []int, i int) (string, int) {
+	if i+2 < len(args) && args[i+1] == 5 {
+		return t.indexed(args[i+2]), i + 2
+	}
+	if i+4 < len(args) && args[i+1] == 2 {
+		clamp := func(v int) uint8 { return uint8(min(max(v, 0), 255)) }
+		return FormatHexColor(clamp(args[i+2]), clamp(args[i+3]), clamp(args[i+4])), i + 4
+	}
+	return "", len(args)
+}
+
+func (t *terminal) indexed(n int) string {
+	n = min(max(n, 0), 255)
+	if n < 16 {
+		return t.basic(n)
+	}
+	r, g, b := xterm256(uint8(n))
+	return FormatHexColor(r, g, b)
+}
+
+// basic returns one of the 16 basic colors in the terminal's palette
+func (t *terminal) basic(n int) string {
+	c := ansi16[n]
+	if t.art {
+		c = vgaPalette[n]
+	}
+	return FormatHexColor(c[0], c[1], c[2])
+}
+
+func (t *terminal) print(r rune) {
+	if t.graphics {
+		if g, ok := decGraphics[r]; ok {
+			r = g
+		}
+	}
+	// Combining marks join the character before them
+	if unicode.Is(unicode.Mn, r) {
+		px, py := t.x-1, t.y
+		if t.wrapPending {
+			px = t.x
+		}
+		if px >= 0 {
+			t.cells[py][px].Char += string(r)
+		}
+		return
+	}
+
+	if t.wrapPending {
+		if t.autowrap {
+			t.x = 0
+			t.lineFeed()
+		}
+		t.wrapPending = false
+	}
+	cell := t.style()
+	cell.Char = string(r)
+	t.cells[t.y][t.x] = cell
+	switch {
+	case t.x < t.width-1:
+		t.x++
+	case t.art && t.autowrap:
+		// DOS wraps as soon as the last column is written
+		t.x = 0
+		t.lineFeed()
+	default:
+		t.wrapPending = true
+	}
+}
+
+// style returns a cell in the current pen, with defaults filled in
+func (t *terminal) style() Cell {
+	fg, bg := t.foreground(), t.background()
+	if t.pen.inverse {
+		fg, bg = bg, fg
+	}
+	bold := t.pen.bold && !t.art
+	return Cell{Char: " ", Foreground: fg, Background: bg, Bold: bold, Italic: t.pen.italic, Underline: t.pen.underline}
+}
+
+func (t *terminal) foreground() string {
+	n := t.pen.fgIndex
+	if n < 0 && t.pen.fg == "" && t.art {
+		n = 7 // light gray
+	}
+	if n < 0 {
+		if t.pen.fg == "" {
+			return blankCell.Foreground
+		}
+		return t.pen.fg
+	}
+	if t.art && t.pen.bold && n < 8 {
+		n += 8
+	}
+	return t.basic(n)
+}
+
+func (t *terminal) background() string {
+	n := t.pen.bgIndex
+	if n < 0 && t.pen.bg == "" && t.art {
+		n = 0 // black
+	}
+	if n < 0 {
+		if t.pen.bg == "" {
+			return blankCell.Background
+		}
+		return t.pen.bg
+	}
+	if t.iceColors && t.pen.blink && n < 8 {
+		n += 8
+	}
+	return t.basic(n)
+}
+
+// blank is an erased cell: a space in the current background
+func (t *terminal) blank() Cell {
+	c := blankCell
+	c.Background = t.background()
+	return c
+}
+
+func (t *terminal) blankScreen() [][]Cell {
+	screen := make([][]Cell, t.height)
+	for y := range screen {
+		screen[y] = t.blankRow()
+	}
+	return screen
+}
+
+func (t *terminal) blankRow() []Cell {
+	row := make([]Cell, t.width)
+	b := t.blank()
+	for x := range row {
+		row[x] = b
+	}
+	return row
+}
+
+func (t *terminal) moveTo(x, y int) {
+	t.x = min(max(x, 0), t.width-1)
+	t.y = min(max(y, 0), t.height-1)
+	t.wrapPending = false
+}
+
+func (t *terminal) lineFeed() {
+	if t.y == t.bottom {
+		t.scrollUp(1)
+	} else if t.y < t.height-1 {
+		t.y++
+	}
+	t.wrapPending = false
+}
+
+func (t *terminal) scrollUp(n int)   { t.scrollRegion(t.top, t.bottom, n) }
+func (t *terminal) scrollDown(n int) { t.scrollRegion(t.top, t.bottom, -n) }
+
+// scrollRegion moves rows top..bottom up by n (down when negative),
+// filling the rows that open up with blanks
+func (t *terminal) scrollRegion(top, bottom, n int) {
+	size := bottom - top + 1
+	if n > 0 {
+		n = min(n, size)
+		copy(t.cells[top:], t.cells[top+n:bottom+1])
+		for y := bottom - n + 1; y <= bottom; y++ {
+			t.cells[y] = t.blankRow()
+		}
+	} else if n < 0 {
+		n = min(-n, size)
+		copy(t.cells[top+n:bottom+1], t.cells[top:bottom+1-n])
+		for y := top; y < top+n; y++ {
+			t.cells[y] = t.blankRow()
+		}
+	}
+}
+
+func (t *terminal) eraseDisplay(mode int) {
+	switch mode {
+	case 0:
+		t.eraseLine(0)
+		for y := t.y + 1; y < t.height; y++ {
+			t.cells[y] = t.blankRow()
+		}
+	case 1:
+		t.eraseLine(1)
+		for y := 0; y < t.y; y++ {
+			t.cells[y] = t.blankRow()
+		}
+	case 2, 3:
+		t.cells = t.blankScreen()
+	}
+}
+
+func (t *terminal) eraseLine(mode int) {
+	from, to := t.x, t.width
+	switch mode {
+	case 1:
+		from, to = 0, t.x+1
+	case 2:
+		from = 0
+	}
+	b := t.blank()
+	for x := from; x < to; x++ {
+		t.cells[t.y][x] = b
+	}
+}
+
+func (t *terminal) insertChars(n int) {
+	row := t.cells[t.y]
+	n = min(n, t.width-t.x)
+	copy(row[t.x+n:], row[t.x:t.width-n])
+	for x := t.x; x < t.x+n; x++ {
+		row[x] = t.blank()
+	}
+}
+
+func (t *terminal) deleteChars(n int) {
+	row := t.cells[t.y]
+	n = min(n, t.width-t.x)
+	copy(row[t.x:], row[t.x+n:])
+	for x := t.width - n; x < t.width; x++ {
+		row[x] = t.blank()
+	}
+}
+
+// decGraphics maps the DEC special graphics set, used for line drawing
+var decGraphics = map[rune]rune{
+	'`': '◆', 'a': '▒', 'f': '°', 'g': '±', 'j': '┘', 'k': '┐', 'l': '┌',
+	'm': '└', 'n': '┼', 'o': '⎺', 'p': '⎻', 'q': '─', 'r': '⎼', 's': '⎽',
+	't': '├', 'u': '┤', 'v': '┴', 'w': '┬', 'x': '│', 'y': '≤', 'z': '≥',
+	'{': 'π', '|': '≠', '}': '£', '~': '·',
+}
@@ -9,45 +9,9 @@ import (
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/mlamkadm/aart/internal/config"
-	"github.com/mlamkadm/aart/internal/fileformat"
+	"github.com/mlamkadm/aart/internal/converter"
 )
 
-// convertAARTToFrames converts fileformat AART to UI frames
-func convertAARTToFrames(aart *fileformat.AartFile) []*Frame {
-	frames := make([]*Frame, aart.FrameCount())
-	
-	for i := 0; i < aart.FrameCount(); i++ {
-		aartFrame := aart.Frames[i]
-		frame := &Frame{
-			Width:  aart.Canvas.Width,
-			Height: aart.Canvas.Height,
-			Delay:  aartFrame.Duration,
-		}
-		
-		// Initialize cells
-		frame.Cells = make([][]Cell, frame.Height)
-		for y := 0; y < frame.Height; y++ {
-			frame.Cells[y] = make([]Cell, frame.Width)
-			for x := 0; x < frame.Width; x++ {
-				if y < len(aartFrame.Cells) && x < len(aartFrame.Cells[y]) {
-					cell := aartFrame.Cells[y][x]
-					frame.Cells[y][x] = Cell{
-						Char: rune(cell.Char[0]), // Convert string to rune
-						FG:   cell.Foreground,
-						BG:   cell.Background,
-					}
-				} else {
-					frame.Cells[y][x] = Cell{Char: ' ', FG: "#FFFFFF", BG: "#000000"}
-				}
-			}
-		}
-		
-		frames[i] = frame
-	}
-	
-	return frames
-}
-
 // FilePickerScreen allows browsing and selecting files
 type FilePickerScreen struct {
 	width         int
@@ -59,7 +23,7 @@ type FilePickerScreen struct {
 	files         []os.FileInfo
 	selectedIndex int
 	showHidden    bool
-	filter        string // ".aart" or "" for all
+	filter        string // comma-separated suffixes, e.g. ".aart,.aartz", or "" for all
 	title         string
 	returnTo      tea.Model
 }
@@ -118,10 +82,8 @@ func (f *FilePickerScreen) loadDirectory() {
 		}
 		
 		// Apply filter
-		if f.filter != "" && !info.IsDir() {
-			if !strings.HasSuffix(entry.Name(), f.filter) {
-				continue
-			}
+		if f.filter != "" && !info.IsDir() && !matchesFilter(entry.Name(), f.filter) {
+			continue
 		}
 		
 		f.files = append(f.files, info)
@@ -197,23 +159,38 @@ func (f FilePickerScreen) handleSelect() (tea.Model, tea.Cmd) {
 	
 	// File selected - load it
 	fullPath := filepath.Join(f.currentDir, selected.Name())
+
+	// Images open the import dialog with the path filled in
+	if converter.IsImageSource(fullPath) {
+		importer := NewImportGIFScreen(f.config, f.returnTo)
+		importer.url = fullPath
+		importer.inputMode = "width"
+		return importer, importer.Init()
+	}
 	
-	// Load the .aart file
-	aartData, err := fileformat.Load(fullPath)
+	// Open the .aart file, packed files decoding frames as they are visited
+	editor, err := OpenFile(f.config, fullPath)
 	if err != nil {
 		// TODO: Show error message
 		return f, nil
 	}
 	
 	// Add to recent files
-	f.config.AddRecentFile(fullPath, aartData.FrameCount())
+	f.config.AddRecentFile(fullPath, len(editor.frames))
 	config.Save(f.config)
 	
-	// Convert to UI frames
-	frames := convertAARTToFrames(aartData)
-	
-	// Open editor with loaded frames
-	return newModelWithConfig(frames, fullPath, f.config), nil
+	// Open editor with loaded frames and layers
+	return editor, nil
+}
+
+// matchesFilter reports whether name ends in one of the filter's suffixes
+func matchesFilter(name, filter string) bool {
+	for _, suffix := range strings.Split(filter, ",") {
+		if strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+	return false
 }
 
 func (f FilePickerScreen) View() string {
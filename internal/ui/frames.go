@@ -0,0 +1,263 @@
+package ui
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Delay bounds for frame timing, in milliseconds
+const (
+	minFrameDelay  = 10
+	maxFrameDelay  = 60000
+	frameDelayStep = 10
+)
+
+// frameTickCmd schedules the next playback tick after delay
+func frameTickCmd(delay time.Duration) tea.Cmd {
+	return tea.Tick(delay, func(t time.Time) tea.Msg {
+		return tickMsg(t)
+	})
+}
+
+// playbackTick schedules the tick that advances past the current frame
+func (m Model) playbackTick() tea.Cmd {
+	return frameTickCmd(time.Duration(m.frameDelay(m.currentFrame)) * time.Millisecond)
+}
+
+// frameDelay returns how long frame i is shown. Frames without their own
+// delay use the global FPS.
+func (m Model) frameDelay(i int) int {
+	if i >= 0 && i < len(m.frames) && m.frames[i].Delay > 0 {
+		return m.frames[i].Delay
+	}
+	if m.fps <= 0 {
+		return 100
+	}
+	return 1000 / m.fps
+}
+
+// copyFrame returns a deep copy of a frame and all its layers
+func copyFrame(f *Frame) *Frame {
+	c := &Frame{Width: f.Width, Height: f.Height, Delay: f.Delay, Name: f.Name, Modified: true}
+	c.Layers = make([][][]Cell, len(f.Layers))
+	for l, grid := range f.Layers {
+		c.Layers[l] = make([][]Cell, len(grid))
+		for y := range grid {
+			c.Layers[l][y] = append([]Cell(nil), grid[y]...)
+		}
+	}
+	return c
+}
+
+// loadFrame decodes frame i when it is still pending in the packed file
+// the editor opened
+func (m *Model) loadFrame(i int) {
+	if i < 0 || i >= len(m.frames) || !m.frames[i].pending {
+		return
+	}
+	frame := m.frames[i]
+	frame.pending = false
+	ff, err := m.source.Frame(frame.sourceIndex)
+	if err != nil {
+		m.statusMsg = fmt.Sprintf("Error reading frame %d: %v", i+1, err)
+		frame.Layers = [][][]Cell{newGrid(frame.Width, frame.Height, opaqueBlank)}
+	} else {
+		frame.Layers = gridsFromAart(m.document, ff)
+	}
+	m.normalizeLayers()
+}
+
+// loadVisibleFrames decodes the frames the editor shows: the current one
+// and its onion skin neighbors
+func (m *Model) loadVisibleFrames() {
+	if m.source == nil {
+		return
+	}
+	m.loadFrame(m.currentFrame)
+	if m.onionSkin {
+		for i := m.currentFrame - m.onionPrev; i <= m.currentFrame+m.onionNext; i++ {
+			m.loadFrame(i)
+		}
+	}
+}
+
+// loadAllFrames decodes every pending frame, before changes that span the
+// whole animation, and closes the packed file once nothing is left to read
+func (m *Model) loadAllFrames() {
+	if m.source == nil {
+		return
+	}
+	for i := range m.frames {
+		m.loadFrame(i)
+	}
+	m.source.Close()
+	m.source = nil
+}
+
+// addFrame inserts a blank frame after (or before) the current one
+func (m *Model) addFrame(before bool) {
+	cur := m.frames[m.currentFrame]
+	frame := m.newFrame(cur.Width, cur.Height)
+	frame.Delay = cur.Delay
+	frame.Modified = true
+	at := m.currentFrame + 1
+	if before {
+		at = m.currentFrame
+	}
+	m.beginEdit("new frame")
+	m.insertFrame(at, frame)
+	m.commitEdit()
+	m.statusMsg = fmt.Sprintf("New frame %d/%d", at+1, len(m.frames))
+}
+
+// duplicateFrames copies frames from..to and inserts the copies after to
+func (m *Model) duplicateFrames(from, to int) {
+	m.beginEdit("duplicate frame")
+	for i := from; i <= to; i++ {
+		m.loadFrame(i)
+		m.insertFrame(to+1+i-from, copyFrame(m.frames[i]))
+	}
+	m.commitEdit()
+	m.statusMsg = fmt.Sprintf("Duplicated %d frame(s)", to-from+1)
+}
+
+// deleteFrames removes frames from..to, always keeping at least one frame
+func (m *Model) deleteFrames(from, to int) {
+	if to-from+1 >= len(m.frames) {
+		m.statusMsg = "Cannot delete every frame"
+		return
+	}
+	m.beginEdit("delete frame")
+	for i := to; i >= from; i-- {
+		m.deleteFrame(i)
+	}
+	m.commitEdit()
+	m.currentFrame = min(from, len(m.frames)-1)
+	m.clampCursor()
+	m.statusMsg = fmt.Sprintf("Deleted %d frame(s)", to-from+1)
+}
+
+// moveFrame moves the current frame to index to
+func (m *Model) moveFrame(to int) {
+	from := m.currentFrame
+	if to < 0 || to >= len(m.frames) || to == from {
+		return
+	}
+	frame := m.frames[from]
+	m.beginEdit("move frame")
+	m.deleteFrame(from)
+	m.insertFrame(to, frame)
+	m.commitEdit()
+	m.statusMsg = fmt.Sprintf("Moved frame to %d/%d", to+1, len(m.frames))
+}
+
+// setFrameDelays sets the delay of frames from..to
+func (m *Model) setFrameDelays(from, to, delay int) {
+	delay = max(minFrameDelay, min(delay, maxFrameDelay))
+	m.beginEdit("delay")
+	for i := from; i <= to; i++ {
+		m.setFrameDelay(i, delay)
+	}
+	m.commitEdit()
+	m.statusMsg = fmt.Sprintf("Delay %dms on %d frame(s)", delay, to-from+1)
+}
+
+// nudgeDelay changes the current frame delay by delta milliseconds
+func (m *Model) nudgeDelay(delta int) {
+	m.setFrameDelays(m.currentFrame, m.currentFrame, m.frameDelay(m.currentFrame)+delta)
+}
+
+// frameCommand handles :frame subcommands on the current frame
+func (m *Model) frameCommand(parts []string) {
+	if len(parts) < 2 {
+		m.statusMsg = fmt.Sprintf("Frame %d/%d, %dms", m.currentFrame+1, len(m.frames), m.frameDelay(m.currentFrame))
+		return
+	}
+	arg := ""
+	if len(parts) > 2 {
+		arg = parts[2]
+	}
+
+	switch parts[1] {
+	case "new", "add":
+		m.addFrame(arg == "before")
+	case "dup", "duplicate":
+		m.duplicateFrames(m.currentFrame, m.currentFrame)
+	case "del", "delete", "rm":
+		m.deleteFrames(m.currentFrame, m.currentFrame)
+	case "move", "mv":
+		to := m.currentFrame
+		switch arg {
+		case "left":
+			to--
+		case "right":
+			to++
+		default:
+			n, err := strconv.Atoi(arg)
+			if err != nil {
+				m.statusMsg = "Usage: :frame move left|right|N"
+				return
+			}
+			to = n - 1
+		}
+		if to < 0 || to >= len(m.frames) {
+			m.statusMsg = "Frame cannot move there"
+			return
+		}
+		m.moveFrame(to)
+	case "delay":
+		delay, err := strconv.Atoi(strings.TrimSuffix(arg, "ms"))
+		if err != nil {
+			m.statusMsg = "Usage: :frame delay MS"
+			return
+		}
+		m.setFrameDelays(m.currentFrame, m.currentFrame, delay)
+	default:
+		n, err := strconv.Atoi(parts[1])
+		if err != nil || n < 1 || n > len(m.frames) {
+			m.statusMsg = "Usage: :frame [N|new [before]|dup|del|move left|right|N|delay MS]"
+			return
+		}
+		m.currentFrame = n - 1
+		m.clampCursor()
+	}
+}
+
+// framesCommand handles :frames RANGE ACTION, e.g. ":frames 10-20 delete"
+// or ":frames 1-5 delay 120". Ranges are 1-based and inclusive.
+func (m *Model) framesCommand(parts []string) {
+	usage := "Usage: :frames N-M delete|dup|delay MS"
+	if len(parts) < 3 {
+		m.statusMsg = usage
+		return
+	}
+	from, to, err := parseFrameRange(parts[1], len(m.frames))
+	if err != nil {
+		m.statusMsg = err.Error()
+		return
+	}
+
+	switch parts[2] {
+	case "del", "delete", "rm":
+		m.deleteFrames(from, to)
+	case "dup", "duplicate":
+		m.duplicateFrames(from, to)
+	case "delay":
+		if len(parts) < 4 {
+			m.statusMsg = usage
+			return
+		}
+		delay, err := strconv.Atoi(strings.TrimSuffix(parts[3], "ms"))
+		if err != nil {
+			m.statusMsg = usage
+			return
+		}
+		m.setFrameDelays(from, to, delay)
+	default:
+		m.statusMsg = usage
+	}
+}
@@ -0,0 +1,501 @@
+package ui
+
+import "fmt"
+
+// History records editor changes as grouped transactions so they can be
+// undone and redone. Memory use is bounded by a byte budget: once the
+// recorded transactions exceed it, the oldest ones are discarded.
+type History struct {
+	undo    []*transaction
+	redo    []*transaction
+	pending *transaction
+	budget  int // bytes, <= 0 = unlimited
+	used    int
+}
+
+type opKind int
+
+const (
+	opCells opKind = iota
+	opFrameInsert
+	opFrameDelete
+	opFrameDelay
+	opLayers
+)
+
+// cellChange is a single cell edit with its previous and new contents
+type cellChange struct {
+	Frame  int
+	Layer  int
+	X, Y   int
+	Before Cell
+	After  Cell
+}
+
+// historyOp is one reversible step inside a transaction
+type historyOp struct {
+	kind  opKind
+	cells []cellChange
+
+	// Frame insert/delete
+	index int
+	frame *Frame
+
+	// Frame delay changes
+	delayBefore int
+	delayAfter  int
+
+	// Layer changes
+	before layerState
+	after  layerState
+}
+
+// layerState is a snapshot of the layer stack. stacks holds each frame's
+// layer grids (outer slices only, grids are never modified in place by
+// layer operations); it is nil when only layer settings changed.
+type layerState struct {
+	layers  []Layer
+	current int
+	stacks  [][][][]Cell
+}
+
+// transaction groups the operations of one user action (a stroke, a fill,
+// an insert session...) so they are undone together
+type transaction struct {
+	label string
+	ops   []historyOp
+	size  int
+
+	// index of pending cell edits, used to coalesce repeated edits of a cell
+	cellIndex map[cellKey]int
+}
+
+type cellKey struct {
+	frame, layer, x, y int
+}
+
+// Approximate in-memory sizes used for the budget
+const (
+	cellSize       = 48
+	cellChangeSize = 2*cellSize + 24
+	layerSize      = 64
+	opOverhead     = 96
+)
+
+// NewHistory creates an empty history bounded by budget bytes, unbounded
+// when budget <= 0
+func NewHistory(budget int) *History {
+	return &History{budget: budget}
+}
+
+// Begin starts a new transaction. Any pending transaction is committed first.
+func (h *History) Begin(label string) {
+	h.Commit()
+	h.pending = &transaction{label: label, cellIndex: make(map[cellKey]int)}
+}
+
+// Commit finishes the pending transaction and pushes it on the undo stack
+func (h *History) Commit() {
+	t := h.pending
+	h.pending = nil
+	if t == nil || len(t.ops) == 0 {
+		return
+	}
+	t.cellIndex = nil
+	h.undo = append(h.undo, t)
+	h.used += t.size
+
+	// A new change invalidates everything that was undone
+	for _, r := range h.redo {
+		h.used -= r.size
+	}
+	h.redo = nil
+
+	h.trim()
+}
+
+// InTransaction reports whether a transaction is open
+func (h *History) InTransaction() bool {
+	return h.pending != nil
+}
+
+// CanUndo reports whether there is anything to undo
+func (h *History) CanUndo() bool {
+	return len(h.undo) > 0
+}
+
+// CanRedo reports whether there is anything to redo
+func (h *History) CanRedo() bool {
+	return len(h.redo) > 0
+}
+
+// Usage returns the approximate number of bytes held by the history
+func (h *History) Usage() int {
+	return h.used
+}
+
+// trim drops the oldest transactions until the history fits its budget
+func (h *History) trim() {
+	if h.budget <= 0 {
+		return
+	}
+	drop := 0
+	for h.used > h.budget && drop < len(h.undo)-1 {
+		h.used -= h.undo[drop].size
+		drop++
+	}
+	if drop > 0 {
+		h.undo = append([]*transaction(nil), h.undo[drop:]...)
+	}
+}
+
+// ensure returns the pending transaction, opening an implicit one if needed
+func (h *History) ensure(label string) (*transaction, bool) {
+	if h.pending != nil {
+		return h.pending, false
+	}
+	h.Begin(label)
+	return h.pending, true
+}
+
+// recordCell records a cell edit. Repeated edits of the same cell within a
+// transaction are coalesced into one change.
+func (h *History) recordCell(frame, layer, x, y int, before, after Cell) {
+	t, implicit := h.ensure("edit")
+	key := cellKey{frame, layer, x, y}
+	if idx, ok := t.cellIndex[key]; ok {
+		op := &t.ops[len(t.ops)-1]
+		if op.kind == opCells && idx < len(op.cells) {
+			op.cells[idx].After = after
+			return
+		}
+	}
+
+	if len(t.ops) == 0 || t.ops[len(t.ops)-1].kind != opCells {
+		t.ops = append(t.ops, historyOp{kind: opCells})
+		t.size += opOverhead
+		// Indices refer to the last cell op only
+		t.cellIndex = make(map[cellKey]int)
+	}
+	op := &t.ops[len(t.ops)-1]
+	t.cellIndex[key] = len(op.cells)
+	op.cells = append(op.cells, cellChange{Frame: frame, Layer: layer, X: x, Y: y, Before: before, After: after})
+	t.size += cellChangeSize
+
+	if implicit {
+		h.Commit()
+	}
+}
+
+// recordFrameInsert records that frame was inserted at index
+func (h *History) recordFrameInsert(index int, frame *Frame) {
+	h.recordFrameOp(opFrameInsert, index, frame)
+}
+
+// recordFrameDelete records that frame was removed from index
+func (h *History) recordFrameDelete(index int, frame *Frame) {
+	h.recordFrameOp(opFrameDelete, index, frame)
+}
+
+func (h *History) recordFrameOp(kind opKind, index int, frame *Frame) {
+	t, implicit := h.ensure("frame")
+	t.ops = append(t.ops, historyOp{kind: kind, index: index, frame: frame})
+	t.size += opOverhead + frameSize(frame)
+	if implicit {
+		h.Commit()
+	}
+}
+
+// recordFrameDelay records a change of the delay of the frame at index
+func (h *History) recordFrameDelay(index, before, after int) {
+	t, implicit := h.ensure("delay")
+	t.ops = append(t.ops, historyOp{kind: opFrameDelay, index: index, delayBefore: before, delayAfter: after})
+	t.size += opOverhead
+	if implicit {
+		h.Commit()
+	}
+}
+
+// recordLayers records a change of the layer stack. dropped is the number
+// of cells only kept alive by the history (e.g. grids of a deleted layer).
+func (h *History) recordLayers(before, after layerState, dropped int) {
+	t, implicit := h.ensure("layers")
+	t.ops = append(t.ops, historyOp{kind: opLayers, before: before, after: after})
+	t.size += opOverhead + (len(before.layers)+len(after.layers))*layerSize + dropped*cellSize
+	if implicit {
+		h.Commit()
+	}
+}
+
+func frameSize(f *Frame) int {
+	if f == nil {
+		return 0
+	}
+	return len(f.Layers) * f.Width * f.Height * cellSize
+}
+
+// undoOne pops the newest transaction and reverts it on m
+func (h *History) undoOne(m *Model) (string, bool) {
+	if len(h.undo) == 0 {
+		return "", false
+	}
+	t := h.undo[len(h.undo)-1]
+	h.undo = h.undo[:len(h.undo)-1]
+	for i := len(t.ops) - 1; i >= 0; i-- {
+		t.ops[i].revert(m)
+	}
+	h.redo = append(h.redo, t)
+	return t.label, true
+}
+
+// redoOne pops the newest undone transaction and reapplies it on m
+func (h *History) redoOne(m *Model) (string, bool) {
+	if len(h.redo) == 0 {
+		return "", false
+	}
+	t := h.redo[len(h.redo)-1]
+	h.redo = h.redo[:len(h.redo)-1]
+	for i := range t.ops {
+		t.ops[i].apply(m)
+	}
+	h.undo = append(h.undo, t)
+	return t.label, true
+}
+
+func (op *historyOp) revert(m *Model) {
+	switch op.kind {
+	case opCells:
+		for i := len(op.cells) - 1; i >= 0; i-- {
+			c := op.cells[i]
+			m.putCell(c.Frame, c.Layer, c.X, c.Y, c.Before)
+		}
+	case opFrameInsert:
+		m.removeFrameAt(op.index)
+	case opFrameDelete:
+		m.insertFrameAt(op.index, op.frame)
+	case opFrameDelay:
+		m.putFrameDelay(op.index, op.delayBefore)
+	case opLayers:
+		m.restoreLayers(op.before)
+	}
+}
+
+func (op *historyOp) apply(m *Model) {
+	switch op.kind {
+	case opCells:
+		for _, c := range op.cells {
+			m.putCell(c.Frame, c.Layer, c.X, c.Y, c.After)
+		}
+	case opFrameInsert:
+		m.insertFrameAt(op.index, op.frame)
+	case opFrameDelete:
+		m.removeFrameAt(op.index)
+	case opFrameDelay:
+		m.putFrameDelay(op.index, op.delayAfter)
+	case opLayers:
+		m.restoreLayers(op.after)
+	}
+}
+
+// Model helpers that keep edits and history in sync
+
+// beginEdit opens a transaction for a user action
+func (m *Model) beginEdit(label string) {
+	m.history.Begin(label)
+}
+
+// commitEdit closes the current transaction
+func (m *Model) commitEdit() {
+	m.history.Commit()
+}
+
+// cellAt returns the cell at x,y of the current frame's active layer
+func (m *Model) cellAt(x, y int) (Cell, bool) {
+	frame := m.frames[m.currentFrame]
+	if x < 0 || y < 0 || x >= frame.Width || y >= frame.Height {
+		return Cell{}, false
+	}
+	return frame.Layers[m.currentLayer][y][x], true
+}
+
+// setCell edits a cell of the current frame's active layer and records it
+// in the history
+func (m *Model) setCell(x, y int, cell Cell) {
+	m.setFrameCell(m.currentFrame, x, y, cell)
+}
+
+// setFrameCell edits a cell of the active layer of any frame and records it
+// in the history
+func (m *Model) setFrameCell(frameIdx, x, y int, cell Cell) {
+	if frameIdx < 0 || frameIdx >= len(m.frames) {
+		return
+	}
+	m.loadFrame(frameIdx)
+	frame := m.frames[frameIdx]
+	if x < 0 || y < 0 || x >= frame.Width || y >= frame.Height {
+		return
+	}
+	before := frame.Layers[m.currentLayer][y][x]
+	if before == cell {
+		return
+	}
+	m.history.recordCell(frameIdx, m.currentLayer, x, y, before, cell)
+	m.putCell(frameIdx, m.currentLayer, x, y, cell)
+}
+
+// putCell writes a cell without recording history
+func (m *Model) putCell(frameIdx, layer, x, y int, cell Cell) {
+	if frameIdx < 0 || frameIdx >= len(m.frames) {
+		return
+	}
+	m.loadFrame(frameIdx)
+	frame := m.frames[frameIdx]
+	if layer < 0 || layer >= len(frame.Layers) {
+		return
+	}
+	if x < 0 || y < 0 || x >= frame.Width || y >= frame.Height {
+		return
+	}
+	frame.Layers[layer][y][x] = cell
+	frame.Modified = true
+	m.modified = true
+}
+
+// insertFrame inserts a frame at index and records it in the history
+func (m *Model) insertFrame(index int, frame *Frame) {
+	m.insertFrameAt(index, frame)
+	m.history.recordFrameInsert(index, frame)
+}
+
+// deleteFrame removes the frame at index and records it in the history
+func (m *Model) deleteFrame(index int) {
+	if index < 0 || index >= len(m.frames) {
+		return
+	}
+	frame := m.frames[index]
+	m.removeFrameAt(index)
+	m.history.recordFrameDelete(index, frame)
+}
+
+// setFrameDelay changes the delay of the frame at index and records it in
+// the history
+func (m *Model) setFrameDelay(index, delay int) {
+	if index < 0 || index >= len(m.frames) || m.frames[index].Delay == delay {
+		return
+	}
+	m.history.recordFrameDelay(index, m.frames[index].Delay, delay)
+	m.putFrameDelay(index, delay)
+}
+
+// putFrameDelay changes a frame delay without recording history
+func (m *Model) putFrameDelay(index, delay int) {
+	if index < 0 || index >= len(m.frames) {
+		return
+	}
+	m.frames[index].Delay = delay
+	m.frames[index].Modified = true
+	m.modified = true
+}
+
+func (m *Model) insertFrameAt(index int, frame *Frame) {
+	if index < 0 {
+		index = 0
+	}
+	if index > len(m.frames) {
+		index = len(m.frames)
+	}
+	m.frames = append(m.frames, nil)
+	copy(m.frames[index+1:], m.frames[index:])
+	m.frames[index] = frame
+	m.currentFrame = index
+	m.modified = true
+}
+
+func (m *Model) removeFrameAt(index int) {
+	if index < 0 || index >= len(m.frames) {
+		return
+	}
+	// A frame leaving the list may come back through the history after
+	// the packed file is closed, so it is decoded first
+	m.loadFrame(index)
+	m.frames = append(m.frames[:index], m.frames[index+1:]...)
+	if m.currentFrame >= len(m.frames) {
+		m.currentFrame = len(m.frames) - 1
+	}
+	if m.currentFrame < 0 {
+		m.currentFrame = 0
+	}
+	m.modified = true
+}
+
+func (m *Model) clampLayer() {
+	if m.currentLayer >= len(m.layers) {
+		m.currentLayer = len(m.layers) - 1
+	}
+	if m.currentLayer < 0 {
+		m.currentLayer = 0
+	}
+}
+
+// undo reverts the last n transactions
+func (m *Model) undo(n int) {
+	m.history.Commit()
+	done := 0
+	label := ""
+	for ; done < n; done++ {
+		l, ok := m.history.undoOne(m)
+		if !ok {
+			break
+		}
+		label = l
+	}
+	switch {
+	case done == 0:
+		m.statusMsg = "Already at oldest change"
+	case done == 1:
+		m.statusMsg = fmt.Sprintf("Undo: %s", label)
+	default:
+		m.statusMsg = fmt.Sprintf("Undid %d changes", done)
+	}
+	m.clampCursor()
+}
+
+// redo reapplies the last n undone transactions
+func (m *Model) redo(n int) {
+	m.history.Commit()
+	done := 0
+	label := ""
+	for ; done < n; done++ {
+		l, ok := m.history.redoOne(m)
+		if !ok {
+			break
+		}
+		label = l
+	}
+	switch {
+	case done == 0:
+		m.statusMsg = "Already at newest change"
+	case done == 1:
+		m.statusMsg = fmt.Sprintf("Redo: %s", label)
+	default:
+		m.statusMsg = fmt.Sprintf("Redid %d changes", done)
+	}
+	m.clampCursor()
+}
+
+// clampCursor keeps the cursor inside the current frame
+func (m *Model) clampCursor() {
+	frame := m.frames[m.currentFrame]
+	if m.cursor.X >= frame.Width {
+		m.cursor.X = frame.Width - 1
+	}
+	if m.cursor.Y >= frame.Height {
+		m.cursor.Y = frame.Height - 1
+	}
+	if m.cursor.X < 0 {
+		m.cursor.X = 0
+	}
+	if m.cursor.Y < 0 {
+		m.cursor.Y = 0
+	}
+}
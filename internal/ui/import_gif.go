@@ -11,7 +11,7 @@ import (
 	"github.com/mlamkadm/aart/internal/config"
 )
 
-// ImportGIFScreen handles GIF import with options
+// ImportGIFScreen handles GIF and image import with options
 type ImportGIFScreen struct {
 	width      int
 	height     int
@@ -35,7 +35,7 @@ type ImportGIFScreen struct {
 	ratios       []string
 }
 
-// NewImportGIFScreen creates GIF import dialog
+// NewImportGIFScreen creates the GIF/image import dialog
 func NewImportGIFScreen(cfg *config.Config, returnTo tea.Model) ImportGIFScreen {
 	themeName := cfg.UI.Theme
 	if themeName == "" {
@@ -76,7 +76,7 @@ func NewImportGIFScreen(cfg *config.Config, returnTo tea.Model) ImportGIFScreen
 		ratio:        "fill",
 		inputMode:    "url",
 		cursor:       0,
-		methods:      []string{"luminosity", "average", "block", "dither"},
+		methods:      []string{"luminosity", "average", "block", "dither", "braille", "halfblock", "quadrant"},
 		ratios:       []string{"fill", "fit", "original"},
 	}
 }
@@ -228,7 +228,7 @@ func (g ImportGIFScreen) View() string {
 		Align(lipgloss.Center).
 		Width(80)
 	
-	b.WriteString(titleStyle.Render("🎬 Import GIF to ASCII"))
+	b.WriteString(titleStyle.Render("🎬 Import GIF or Image to ASCII"))
 	b.WriteString("\n\n")
 	
 	labelStyle := lipgloss.NewStyle().
@@ -247,12 +247,12 @@ func (g ImportGIFScreen) View() string {
 	prefix := "  "
 	if g.inputMode == "url" {
 		prefix = "▶ "
-		b.WriteString(activeStyle.Render(prefix + labelStyle.Render("GIF URL/Path:")))
+		b.WriteString(activeStyle.Render(prefix + labelStyle.Render("Image URL/Path:")))
 		b.WriteString(" ")
 		b.WriteString(valueStyle.Render(g.url))
 		b.WriteString(lipgloss.NewStyle().Foreground(g.theme.Cursor).Render("▌"))
 	} else {
-		b.WriteString(labelStyle.Render(prefix + "GIF URL/Path:"))
+		b.WriteString(labelStyle.Render(prefix + "Image URL/Path:"))
 		b.WriteString(" ")
 		if g.url == "" {
 			b.WriteString(lipgloss.NewStyle().Foreground(g.theme.FgMuted).Render("(empty)"))
@@ -343,7 +343,7 @@ func (g ImportGIFScreen) View() string {
 			case "fit":
 				desc = " - Fit inside canvas (preserve ratio)"
 			case "original":
-				desc = " - Use original GIF size"
+				desc = " - Use original image size"
 			}
 			if i == g.cursor {
 				b.WriteString(valueStyle.Render(fmt.Sprintf("     ▶ %s", ratio)))
@@ -423,7 +423,7 @@ func GetTerminalSize() (width, height int) {
 	return width, height
 }
 
-// ImportOptions holds GIF import parameters
+// ImportOptions holds GIF/image import parameters
 type ImportOptions struct {
 	URL    string
 	Width  int
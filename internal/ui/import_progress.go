@@ -22,12 +22,12 @@ func convertConverterToUIFrames(convFrames []*converter.Frame) []*Frame {
 			Delay:  cf.Delay,
 		}
 		
-		// Copy cells
-		uf.Cells = make([][]Cell, cf.Height)
+		// Copy cells into the bottom layer
+		cells := make([][]Cell, cf.Height)
 		for y := 0; y < cf.Height; y++ {
-			uf.Cells[y] = make([]Cell, cf.Width)
+			cells[y] = make([]Cell, cf.Width)
 			for x := 0; x < cf.Width; x++ {
-				uf.Cells[y][x] = Cell{
+				cells[y][x] = Cell{
 					Char: cf.Cells[y][x].Char,
 					FG:   cf.Cells[y][x].FG,
 					BG:   cf.Cells[y][x].BG,
@@ -35,6 +35,7 @@ func convertConverterToUIFrames(convFrames []*converter.Frame) []*Frame {
 			}
 		}
 		
+		uf.Layers = [][][]Cell{cells}
 		uiFrames[i] = uf
 	}
 	
@@ -97,8 +98,8 @@ type importDoneMsg struct {
 
 func (p ImportProgressScreen) startImport() tea.Cmd {
 	return func() tea.Msg {
-		// Import the GIF
-		frames, err := converter.ImportGIF(
+		// Import the GIF or image
+		frames, err := converter.ImportImage(
 			p.opts.URL,
 			p.opts.Width,
 			p.opts.Height,
@@ -193,7 +194,7 @@ func (p ImportProgressScreen) View() string {
 		Align(lipgloss.Center).
 		Width(80)
 	
-	b.WriteString(titleStyle.Render("🎬 Importing GIF"))
+	b.WriteString(titleStyle.Render("🎬 Importing"))
 	b.WriteString("\n\n")
 	
 	// Source info
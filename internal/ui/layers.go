@@ -0,0 +1,478 @@
+package ui
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/mlamkadm/aart/internal/fileformat"
+)
+
+// defaultLayers is the layer stack of a new document
+func defaultLayers() []Layer {
+	return []Layer{
+		{Name: "background", Visible: true, Opacity: 1.0, BlendMode: fileformat.BlendNormal},
+		{Name: "fg_chars", Visible: true, Opacity: 1.0, BlendMode: fileformat.BlendNormal},
+	}
+}
+
+// opaqueBlank is an empty cell that hides the layers below it
+var opaqueBlank = Cell{Char: ' ', FG: "#FFFFFF", BG: "#000000"}
+
+// transparentBlank is an empty cell the layers below show through
+var transparentBlank = Cell{Char: ' '}
+
+// blankCell is what erasing writes on the active layer: the bottom layer is
+// opaque, the layers above it become transparent
+func (m *Model) blankCell() Cell {
+	if m.currentLayer == 0 {
+		return opaqueBlank
+	}
+	return transparentBlank
+}
+
+// newGrid allocates a width x height grid filled with fill
+func newGrid(width, height int, fill Cell) [][]Cell {
+	grid := make([][]Cell, height)
+	for y := range grid {
+		grid[y] = make([]Cell, width)
+		for x := range grid[y] {
+			grid[y][x] = fill
+		}
+	}
+	return grid
+}
+
+// newFrame creates an empty frame with a grid for every layer
+func (m *Model) newFrame(width, height int) *Frame {
+	frame := NewFrame(width, height)
+	padLayers(frame, len(m.layers))
+	return frame
+}
+
+// padLayers adds transparent grids until the frame has n layers
+func padLayers(frame *Frame, n int) {
+	for len(frame.Layers) < n {
+		frame.Layers = append(frame.Layers, newGrid(frame.Width, frame.Height, transparentBlank))
+	}
+}
+
+// normalizeLayers makes every frame carry one grid per layer. Frames with
+// more grids than there are layers get extra layers named after them.
+func (m *Model) normalizeLayers() {
+	for _, frame := range m.frames {
+		if frame.pending {
+			continue
+		}
+		for len(m.layers) < len(frame.Layers) {
+			m.layers = append(m.layers, Layer{
+				Name:      fmt.Sprintf("layer %d", len(m.layers)+1),
+				Visible:   true,
+				Opacity:   1.0,
+				BlendMode: fileformat.BlendNormal,
+			})
+		}
+	}
+	for _, frame := range m.frames {
+		if !frame.pending {
+			padLayers(frame, len(m.layers))
+		}
+	}
+	m.clampLayer()
+}
+
+// toFileCell converts an editor cell to the file format
+func toFileCell(c Cell) fileformat.Cell {
+	char := ""
+	if c.Char != 0 {
+		char = c.glyph()
+	}
+	return fileformat.Cell{
+		Char:       char,
+		Foreground: c.FG,
+		Background: c.BG,
+		Bold:       c.Bold,
+		Italic:     c.Italic,
+		Underline:  c.Underline,
+	}
+}
+
+// fromFileCell converts a file format cell to an editor cell. Char is the
+// first rune; a longer grapheme is kept whole in Text so saving writes it
+// back unchanged.
+func fromFileCell(c fileformat.Cell) Cell {
+	char := ' '
+	text := ""
+	if r := []rune(c.Char); len(r) > 0 {
+		char = r[0]
+		if len(r) > 1 {
+			text = c.Char
+		}
+	}
+	return Cell{
+		Char:      char,
+		Text:      text,
+		FG:        c.Foreground,
+		BG:        c.Background,
+		Bold:      c.Bold,
+		Italic:    c.Italic,
+		Underline: c.Underline,
+	}
+}
+
+// fileLayers converts the layer stack to the file format
+func fileLayers(layers []Layer) []fileformat.Layer {
+	out := make([]fileformat.Layer, len(layers))
+	for i, l := range layers {
+		out[i] = fileformat.Layer(l)
+	}
+	return out
+}
+
+// composite flattens the visible layers of a frame into the grid that is
+// displayed and exported
+func (m Model) composite(frame *Frame) [][]Cell {
+	out := newGrid(frame.Width, frame.Height, opaqueBlank)
+	for i, grid := range frame.Layers {
+		if i >= len(m.layers) {
+			break
+		}
+		layer := fileformat.Layer(m.layers[i])
+		if !layer.Visible || layer.Opacity <= 0 {
+			continue
+		}
+		for y := range out {
+			for x := range out[y] {
+				src := grid[y][x]
+				if src == transparentBlank {
+					continue
+				}
+				out[y][x] = fromFileCell(fileformat.CompositeCell(toFileCell(out[y][x]), toFileCell(src), layer))
+			}
+		}
+	}
+	return out
+}
+
+// compositeAt returns the displayed cell at x,y of the current frame
+func (m Model) compositeAt(x, y int) (Cell, bool) {
+	frame := m.frames[m.currentFrame]
+	if x < 0 || y < 0 || x >= frame.Width || y >= frame.Height {
+		return Cell{}, false
+	}
+	out := opaqueBlank
+	for i, grid := range frame.Layers {
+		if i >= len(m.layers) {
+			break
+		}
+		out = fromFileCell(fileformat.CompositeCell(toFileCell(out), toFileCell(grid[y][x]), fileformat.Layer(m.layers[i])))
+	}
+	return out, true
+}
+
+// framesFromAart converts a loaded file to editor frames and layers. Files
+// without per-layer data load their flat cells as the bottom layer. With
+// lazy the frames carry no cells yet and are decoded by loadFrame.
+func framesFromAart(aart *fileformat.AartFile, lazy bool) ([]*Frame, []Layer) {
+	layers := make([]Layer, len(aart.Layers))
+	for i, l := range aart.Layers {
+		layers[i] = Layer(l)
+		if !fileformat.ValidBlendMode(layers[i].BlendMode) {
+			layers[i].BlendMode = fileformat.BlendNormal
+		}
+	}
+
+	width, height := aart.Canvas.Width, aart.Canvas.Height
+	frames := make([]*Frame, len(aart.Frames))
+	for i := range aart.Frames {
+		ff := &aart.Frames[i]
+		frame := &Frame{Width: width, Height: height, Delay: ff.Duration, Name: ff.Name}
+		if lazy {
+			frame.pending, frame.sourceIndex = true, i
+		} else {
+			frame.Layers = gridsFromAart(aart, ff)
+		}
+		frames[i] = frame
+	}
+
+	if len(layers) == 0 {
+		layers = defaultLayers()
+	}
+	return frames, layers
+}
+
+// gridsFromAart converts the layer grids of a file frame to editor grids
+// of the canvas size
+func gridsFromAart(aart *fileformat.AartFile, ff *fileformat.Frame) [][][]Cell {
+	width, height := aart.Canvas.Width, aart.Canvas.Height
+	var grids [][][]Cell
+	for l, src := range aart.LayerGrids(ff) {
+		fill := opaqueBlank
+		if l > 0 {
+			fill = transparentBlank
+		}
+		grid := newGrid(width, height, fill)
+		for y := 0; y < height && y < len(src); y++ {
+			for x := 0; x < width && x < len(src[y]); x++ {
+				grid[y][x] = fromFileCell(src[y][x])
+			}
+		}
+		grids = append(grids, grid)
+	}
+	return grids
+}
+
+// snapshotLayers captures the layer stack for the history. Grids are shared,
+// so layer operations must replace grids instead of editing them in place.
+func (m *Model) snapshotLayers(withGrids bool) layerState {
+	s := layerState{layers: append([]Layer(nil), m.layers...), current: m.currentLayer}
+	if withGrids {
+		s.stacks = make([][][][]Cell, len(m.frames))
+		for i, frame := range m.frames {
+			s.stacks[i] = append([][][]Cell(nil), frame.Layers...)
+		}
+	}
+	return s
+}
+
+// restoreLayers puts back a snapshot taken by snapshotLayers
+func (m *Model) restoreLayers(s layerState) {
+	m.layers = append([]Layer(nil), s.layers...)
+	m.currentLayer = s.current
+	if s.stacks != nil {
+		for i, frame := range m.frames {
+			if i < len(s.stacks) {
+				frame.Layers = append([][][]Cell(nil), s.stacks[i]...)
+			}
+		}
+	}
+	m.clampLayer()
+	m.modified = true
+}
+
+// changeLayers runs fn on the layer stack and records the change in the
+// history. dropped is the number of grids fn discards per frame.
+func (m *Model) changeLayers(label string, withGrids bool, dropped int, fn func()) {
+	if withGrids {
+		m.loadAllFrames()
+	}
+	before := m.snapshotLayers(withGrids)
+	fn()
+	m.history.Begin(label)
+	cells := 0
+	if len(m.frames) > 0 {
+		cells = dropped * len(m.frames) * m.frames[0].Width * m.frames[0].Height
+	}
+	m.history.recordLayers(before, m.snapshotLayers(withGrids), cells)
+	m.history.Commit()
+	m.clampLayer()
+	m.modified = true
+}
+
+// layerCommand handles :layer subcommands
+func (m *Model) layerCommand(parts []string) {
+	if len(parts) < 2 {
+		m.listLayers()
+		return
+	}
+	arg := strings.Join(parts[2:], " ")
+
+	switch parts[1] {
+	case "add", "new":
+		name := arg
+		if name == "" {
+			name = fmt.Sprintf("layer %d", len(m.layers)+1)
+		}
+		at := m.currentLayer + 1
+		m.changeLayers("add layer", true, 0, func() {
+			m.layers = insertAt(m.layers, at, Layer{Name: name, Visible: true, Opacity: 1.0, BlendMode: fileformat.BlendNormal})
+			for _, frame := range m.frames {
+				frame.Layers = insertAt(frame.Layers, at, newGrid(frame.Width, frame.Height, transparentBlank))
+			}
+			m.currentLayer = at
+		})
+		m.statusMsg = fmt.Sprintf("Added layer %q", name)
+
+	case "del", "delete", "rm":
+		if len(m.layers) <= 1 {
+			m.statusMsg = "Cannot delete the last layer"
+			return
+		}
+		at := m.currentLayer
+		name := m.layers[at].Name
+		m.changeLayers("delete layer", true, 1, func() {
+			m.layers = removeAt(m.layers, at)
+			for _, frame := range m.frames {
+				frame.Layers = removeAt(frame.Layers, at)
+			}
+		})
+		m.statusMsg = fmt.Sprintf("Deleted layer %q", name)
+
+	case "move", "mv":
+		from := m.currentLayer
+		to := from
+		switch arg {
+		case "up":
+			to++
+		case "down":
+			to--
+		default:
+			n, err := strconv.Atoi(arg)
+			if err != nil {
+				m.statusMsg = "Usage: :layer move up|down|N"
+				return
+			}
+			to = n - 1
+		}
+		if to < 0 || to >= len(m.layers) || to == from {
+			m.statusMsg = "Layer cannot move there"
+			return
+		}
+		m.changeLayers("move layer", true, 0, func() {
+			m.layers = insertAt(removeAt(m.layers, from), to, m.layers[from])
+			for _, frame := range m.frames {
+				grid := frame.Layers[from]
+				frame.Layers = insertAt(removeAt(frame.Layers, from), to, grid)
+			}
+			m.currentLayer = to
+		})
+		m.statusMsg = fmt.Sprintf("Moved layer to %d/%d", to+1, len(m.layers))
+
+	case "merge":
+		at := m.currentLayer
+		if at == 0 {
+			m.statusMsg = "No layer below to merge into"
+			return
+		}
+		upper := fileformat.Layer(m.layers[at])
+		m.changeLayers("merge layer", true, 2, func() {
+			for _, frame := range m.frames {
+				merged := newGrid(frame.Width, frame.Height, transparentBlank)
+				for y := range merged {
+					for x := range merged[y] {
+						merged[y][x] = mergeCell(frame.Layers[at-1][y][x], frame.Layers[at][y][x], upper)
+					}
+				}
+				frame.Layers = removeAt(frame.Layers, at)
+				frame.Layers[at-1] = merged
+			}
+			m.layers = removeAt(m.layers, at)
+			m.currentLayer = at - 1
+		})
+		m.statusMsg = fmt.Sprintf("Merged into %q", m.layers[at-1].Name)
+
+	case "flatten":
+		dropped := len(m.layers)
+		m.changeLayers("flatten", true, dropped, func() {
+			for _, frame := range m.frames {
+				frame.Layers = [][][]Cell{m.composite(frame)}
+			}
+			m.layers = []Layer{{Name: "background", Visible: true, Opacity: 1.0, BlendMode: fileformat.BlendNormal}}
+			m.currentLayer = 0
+		})
+		m.statusMsg = fmt.Sprintf("Flattened %d layers", dropped)
+
+	case "show", "hide", "toggle":
+		visible := parts[1] == "show" || (parts[1] == "toggle" && !m.layers[m.currentLayer].Visible)
+		m.changeLayers("layer visibility", false, 0, func() {
+			m.layers[m.currentLayer].Visible = visible
+		})
+		m.listLayers()
+
+	case "opacity":
+		v, err := strconv.ParseFloat(arg, 64)
+		if err != nil || v < 0 || v > 1 {
+			m.statusMsg = "Usage: :layer opacity 0.0-1.0"
+			return
+		}
+		m.changeLayers("layer opacity", false, 0, func() {
+			m.layers[m.currentLayer].Opacity = v
+		})
+		m.listLayers()
+
+	case "blend":
+		if !fileformat.ValidBlendMode(arg) {
+			m.statusMsg = "Blend modes: " + strings.Join(fileformat.BlendModes, ", ")
+			return
+		}
+		m.changeLayers("layer blend", false, 0, func() {
+			m.layers[m.currentLayer].BlendMode = arg
+		})
+		m.listLayers()
+
+	case "rename":
+		if arg == "" {
+			m.statusMsg = "Usage: :layer rename NAME"
+			return
+		}
+		m.changeLayers("rename layer", false, 0, func() {
+			m.layers[m.currentLayer].Name = arg
+		})
+		m.listLayers()
+
+	default:
+		n, err := strconv.Atoi(parts[1])
+		if err != nil || n < 1 || n > len(m.layers) {
+			m.statusMsg = "Usage: :layer [N|add|del|move|merge|flatten|show|hide|toggle|opacity|blend|rename]"
+			return
+		}
+		m.currentLayer = n - 1
+		m.listLayers()
+	}
+}
+
+// mergeCell draws the upper layer cell onto the lower one. Where both are
+// transparent the result stays transparent, so the merged layer still lets
+// the layers below show through.
+func mergeCell(lower, upper Cell, layer fileformat.Layer) Cell {
+	if upper == transparentBlank {
+		return lower
+	}
+	return fromFileCell(fileformat.CompositeCell(toFileCell(lower), toFileCell(upper), layer))
+}
+
+// listLayers shows the layer stack in the status line, active layer marked
+func (m *Model) listLayers() {
+	names := make([]string, len(m.layers))
+	for i, l := range m.layers {
+		mark := " "
+		if i == m.currentLayer {
+			mark = "*"
+		}
+		names[i] = fmt.Sprintf("%s%d:%s%s", mark, i+1, l.Name, layerFlags(l))
+	}
+	m.statusMsg = strings.Join(names, "  ")
+}
+
+// layerFlags describes the non-default settings of a layer
+func layerFlags(l Layer) string {
+	var flags []string
+	if !l.Visible {
+		flags = append(flags, "hidden")
+	}
+	if l.Opacity < 1 {
+		flags = append(flags, fmt.Sprintf("%.0f%%", l.Opacity*100))
+	}
+	if l.BlendMode != "" && l.BlendMode != fileformat.BlendNormal {
+		flags = append(flags, l.BlendMode)
+	}
+	if len(flags) == 0 {
+		return ""
+	}
+	return "(" + strings.Join(flags, ",") + ")"
+}
+
+// insertAt returns a new slice with v inserted at index i
+func insertAt[T any](s []T, i int, v T) []T {
+	out := make([]T, 0, len(s)+1)
+	out = append(out, s[:i]...)
+	out = append(out, v)
+	return append(out, s[i:]...)
+}
+
+// removeAt returns a new slice without the element at index i
+func removeAt[T any](s []T, i int) []T {
+	out := make([]T, 0, len(s))
+	out = append(out, s[:i]...)
+	return append(out, s[i+1:]...)
+}
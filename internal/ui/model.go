@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
@@ -19,6 +21,7 @@ const (
 	ModeNormal Mode = iota
 	ModeCommand
 	ModeInsert
+	ModeVisual
 )
 
 type WheelState int
@@ -57,24 +60,42 @@ const (
 )
 
 var toolNames = []string{"pencil", "fill", "select", "line", "box", "text", "eyedropper", "move"}
-var toolKeys = []string{"p", "f", "s", "l", "b", "t", "e", "m"}
+var toolKeys = []string{"p", "f", "s", "L", "b", "t", "e", "m"} // l is taken by cursor movement
 
 type Pos struct {
 	X, Y int
 }
 
 type Cell struct {
-	Char rune
-	FG   string
-	BG   string
+	Char      rune
+	Text      string // the whole grapheme of a loaded cell when it is more than Char, such as a letter and its combining marks
+	FG        string
+	BG        string
+	Bold      bool
+	Italic    bool
+	Underline bool
+}
+
+// glyph returns the cell's full character: Text when set, otherwise Char
+func (c Cell) glyph() string {
+	if c.Text != "" {
+		return c.Text
+	}
+	return string(c.Char)
 }
 
 type Frame struct {
 	Width    int
 	Height   int
-	Cells    [][]Cell
+	Layers   [][][]Cell // one grid per layer, bottom layer first
 	Modified bool
 	Delay    int // milliseconds per frame
+	Name     string
+
+	// A frame of a packed file is decoded when first visited: until then
+	// it is pending and has no Layers, see loadFrame
+	pending     bool
+	sourceIndex int
 }
 
 type Layer struct {
@@ -82,7 +103,6 @@ type Layer struct {
 	Visible  bool
 	Opacity  float64
 	BlendMode string
-	Frame    *Frame
 }
 
 type Wheel struct {
@@ -107,6 +127,11 @@ type Model struct {
 	fgColor      string
 	bgColor      string
 	brushSize    int
+	toolAnchor   *Pos      // first point of line/box/move
+	fillConnectivity int   // 4 or 8
+	fillMatch    FillMatch
+	boxStyle     int       // index into borderSets
+	stampText    string    // text tool stamp
 	
 	// Wheel
 	wheel      *Wheel
@@ -115,6 +140,11 @@ type Model struct {
 	layers     []Layer
 	currentLayer int
 	
+	// Onion skin
+	onionSkin  bool
+	onionPrev  int
+	onionNext  int
+	
 	// UI state
 	showGrid   bool
 	zoom       float64
@@ -129,9 +159,21 @@ type Model struct {
 	command    string
 	statusMsg  string
 	
+	// Undo/redo
+	history    *History
+	
+	// Selection and registers
+	selection       *Selection
+	registers       Registers
+	pendingRegister rune // register chosen with the " prefix
+	awaitRegister   bool
+	
 	// Misc
 	modified   bool
 	filename   string
+	document   *fileformat.AartFile // loaded file, keeps the fields the editor does not edit
+	source     fileformat.FrameSource // packed file the pending frames are read from
+	keyframeInterval int            // delta-encode frames on save, 0 = off
 }
 
 type tickMsg time.Time
@@ -185,7 +227,7 @@ func NewWithConfig(cfg *config.Config) Model {
 			if x >= width {
 				break
 			}
-			frame.Cells[y][x] = Cell{Char: r, FG: cfg.Colors.Foreground, BG: cfg.Colors.Background}
+			frame.Layers[0][y][x] = Cell{Char: r, FG: cfg.Colors.Foreground, BG: cfg.Colors.Background}
 		}
 	}
 	
@@ -201,46 +243,33 @@ func NewWithConfig(cfg *config.Config) Model {
 
 // NewWithFile creates a model from a loaded .aa file
 func NewWithFile(cfg *config.Config, filename string, aartFile *fileformat.AartFile) Model {
+	return newFileModel(cfg, filename, aartFile, nil)
+}
+
+// OpenFile opens a .aart file in the editor. A packed file stays open and
+// its frames are decoded as they are visited rather than all up front.
+func OpenFile(cfg *config.Config, filename string) (Model, error) {
+	src, err := fileformat.Open(filename)
+	if err != nil {
+		return Model{}, err
+	}
+	if _, packed := src.(*fileformat.PackedReader); !packed {
+		return NewWithFile(cfg, filename, src.Header()), nil
+	}
+	return newFileModel(cfg, filename, src.Header(), src), nil
+}
+
+// newFileModel creates a model from a file. With a source, the frames are
+// read from it as they are visited.
+func newFileModel(cfg *config.Config, filename string, aartFile *fileformat.AartFile, source fileformat.FrameSource) Model {
 	// Use defaults if no config provided
 	if cfg == nil {
 		c := config.DefaultConfig
 		cfg = &c
 	}
 	
-	// Convert fileformat frames to internal frames
-	frames := make([]*Frame, len(aartFile.Frames))
-	
-	for i, ffFrame := range aartFile.Frames {
-		frame := NewFrame(aartFile.Canvas.Width, aartFile.Canvas.Height)
-		
-		// Copy cells from fileformat to internal format
-		for y := 0; y < len(ffFrame.Cells) && y < aartFile.Canvas.Height; y++ {
-			for x := 0; x < len(ffFrame.Cells[y]) && x < aartFile.Canvas.Width; x++ {
-				ffCell := ffFrame.Cells[y][x]
-				var char rune
-				if ffCell.Char == "" {
-					char = ' '
-				} else {
-					runes := []rune(ffCell.Char)
-					if len(runes) > 0 {
-						char = runes[0]
-					} else {
-						char = ' '
-					}
-				}
-				
-				frame.Cells[y][x] = Cell{
-					Char: char,
-					FG:   ffCell.Foreground,
-					BG:   ffCell.Background,
-				}
-			}
-		}
-		
-		frame.Modified = false
-		frame.Delay = ffFrame.Duration
-		frames[i] = frame
-	}
+	// Convert fileformat frames and layers to internal ones
+	frames, layers := framesFromAart(aartFile, source != nil)
 	
 	// If no frames, create one empty frame
 	if len(frames) == 0 {
@@ -259,8 +288,11 @@ func NewWithFile(cfg *config.Config, filename string, aartFile *fileformat.AartF
 		}
 	}
 	
-	model := newModelWithConfig(frames, filename, cfg)
+	model := newModelWithLayers(frames, layers, filename, cfg)
 	model.fps = fps
+	model.document = aartFile
+	model.source = source
+	model.loadVisibleFrames()
 	return model
 }
 
@@ -284,17 +316,19 @@ func NewWithFramesAndConfig(importedFrames []ImportedFrame, cfg *config.Config)
 	// Convert imported frames to internal format
 	frames := make([]*Frame, len(importedFrames))
 	for i, imported := range importedFrames {
+		cells := make([][]Cell, imported.Height)
 		frame := &Frame{
 			Width:    imported.Width,
 			Height:   imported.Height,
-			Cells:    make([][]Cell, imported.Height),
+			Layers:   [][][]Cell{cells},
 			Modified: false,
+			Delay:    imported.Delay,
 		}
 		
 		for y := 0; y < imported.Height; y++ {
-			frame.Cells[y] = make([]Cell, imported.Width)
+			cells[y] = make([]Cell, imported.Width)
 			for x := 0; x < imported.Width; x++ {
-				frame.Cells[y][x] = Cell{
+				cells[y][x] = Cell{
 					Char: imported.Cells[y][x].Char,
 					FG:   imported.Cells[y][x].FG,
 					BG:   imported.Cells[y][x].BG,
@@ -328,6 +362,12 @@ func newModel(frames []*Frame, filename string) Model {
 }
 
 func newModelWithConfig(frames []*Frame, filename string, cfg *config.Config) Model {
+	return newModelWithLayers(frames, defaultLayers(), filename, cfg)
+}
+
+// newModelWithLayers creates a model whose frames carry the given layers.
+// Frames with fewer grids than layers are padded with transparent ones.
+func newModelWithLayers(frames []*Frame, layers []Layer, filename string, cfg *config.Config) Model {
 	// Use defaults if no config provided
 	if cfg == nil {
 		c := config.DefaultConfig
@@ -342,7 +382,7 @@ func newModelWithConfig(frames []*Frame, filename string, cfg *config.Config) Mo
 	theme := GetTheme(themeName)
 	styles := NewStyles(theme)
 
-	return Model{
+	m := Model{
 		mode:         ModeNormal,
 		cursor:       Pos{X: 40, Y: 12},
 		frames:       frames,
@@ -354,33 +394,35 @@ func newModelWithConfig(frames []*Frame, filename string, cfg *config.Config) Mo
 		fgColor:      cfg.Colors.Foreground,
 		bgColor:      cfg.Colors.Background,
 		brushSize:    1,
+		fillConnectivity: 4,
+		boxStyle:     1,
 		zoom:         1.0,
 		showGrid:     cfg.Editor.ShowGrid,
+		onionSkin:    cfg.Editor.OnionSkin,
+		onionPrev:    min(cfg.Editor.OnionPrev, maxOnionFrames),
+		onionNext:    min(cfg.Editor.OnionNext, maxOnionFrames),
+		keyframeInterval: cfg.KeyframeInterval(),
 		zenMode:      cfg.Editor.ZenMode,
 		theme:        theme,
 		styles:       styles,
 		breathing:    NewBreathingEffect(3 * time.Second),
 		filename:     filename,
-		layers: []Layer{
-			{Name: "background", Visible: true, Opacity: 1.0, BlendMode: "normal"},
-			{Name: "fg_chars", Visible: true, Opacity: 1.0, BlendMode: "normal"},
-		},
-		currentLayer: 1,
+		layers:       layers,
+		currentLayer: len(layers) - 1,
+		history:      NewHistory(cfg.Editor.UndoMemoryMB * 1024 * 1024),
+		registers:    make(Registers),
+		document:     fileformat.NewAartFile(frames[0].Width, frames[0].Height, ""),
 	}
+	m.normalizeLayers()
+	return m
 }
 
+// NewFrame creates a blank frame with a single opaque layer
 func NewFrame(width, height int) *Frame {
-	cells := make([][]Cell, height)
-	for y := 0; y < height; y++ {
-		cells[y] = make([]Cell, width)
-		for x := 0; x < width; x++ {
-			cells[y][x] = Cell{Char: ' ', FG: "#FFFFFF", BG: "#000000"}
-		}
-	}
 	return &Frame{
 		Width:  width,
 		Height: height,
-		Cells:  cells,
+		Layers: [][][]Cell{newGrid(width, height, opaqueBlank)},
 	}
 }
 
@@ -389,6 +431,17 @@ func (m Model) Init() tea.Cmd {
 }
 
 func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	// Frames of a packed file are decoded as they come into view
+	m.loadVisibleFrames()
+	next, cmd := m.update(msg)
+	if model, ok := next.(Model); ok {
+		model.loadVisibleFrames()
+		return model, cmd
+	}
+	return next, cmd
+}
+
+func (m Model) update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
@@ -403,12 +456,14 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m.handleCommandMode(msg)
 		case ModeInsert:
 			return m.handleInsertMode(msg)
+		case ModeVisual:
+			return m.handleVisualMode(msg)
 		}
 		
 	case tickMsg:
 		if m.playing {
 			m.currentFrame = (m.currentFrame + 1) % len(m.frames)
-			return m, tickCmd()
+			return m, m.playbackTick()
 		}
 	}
 	
@@ -416,6 +471,15 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 }
 
 func (m Model) handleNormalMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	// Second key of a "x register prefix
+	if m.awaitRegister {
+		m.awaitRegister = false
+		if r := []rune(msg.String()); len(r) == 1 {
+			m.pendingRegister = r[0]
+		}
+		return m, nil
+	}
+	
 	switch msg.String() {
 	case "q", "ctrl+c":
 		return m, tea.Quit
@@ -445,7 +509,12 @@ func (m Model) handleNormalMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		}
 		
 	case "esc":
-		if m.wheel != nil {
+		if m.toolAnchor != nil {
+			m.toolAnchor = nil
+			m.statusMsg = ""
+		} else if m.selection != nil {
+			m.selection = nil
+		} else if m.wheel != nil {
 			if m.wheel.State == WheelExpanded {
 				m.wheel.State = WheelCycling
 			} else {
@@ -473,31 +542,48 @@ func (m Model) handleNormalMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		
 	// Drawing
 	case "d":
-		frame := m.frames[m.currentFrame]
-		frame.Cells[m.cursor.Y][m.cursor.X] = Cell{
-			Char: m.fgChar,
-			FG:   m.fgColor,
-			BG:   m.bgColor,
-		}
-		frame.Modified = true
-		m.modified = true
+		m.applyTool()
 		
 	case "i":
 		m.mode = ModeInsert
+		m.beginEdit("insert")
+		
+	// Selection and registers
+	case "v":
+		m.startVisual(SelectChar)
+	case "V":
+		m.startVisual(SelectLine)
+	case "ctrl+v":
+		m.startVisual(SelectBlock)
+	case "\"":
+		m.awaitRegister = true
+	case "P", "ctrl+p":
+		reg := m.takeRegister()
+		r := m.registers[reg]
+		if r == nil {
+			m.statusMsg = fmt.Sprintf("Register \"%c is empty", reg)
+			break
+		}
+		m.beginEdit("paste")
+		m.pasteRegister(m.currentFrame, r, m.cursor.X, m.cursor.Y, msg.String() == "ctrl+p")
+		m.commitEdit()
+		
+	// Undo/redo
+	case "u":
+		m.undo(1)
+	case "ctrl+r":
+		m.redo(1)
 		
 	// Tool selection
-	case "p":
-		m.selectedTool = ToolPencil
-	case "f":
-		m.selectedTool = ToolFill
-	case "s":
-		m.selectedTool = ToolSelect
+	case "p", "f", "s", "L", "b", "t", "e", "m":
+		t, _ := toolByName(msg.String())
+		m.selectTool(t)
 		
 	// Playback
 	case " ":
 		m.playing = !m.playing
 		if m.playing {
-			return m, tickCmd()
+			return m, m.playbackTick()
 		}
 		
 	case ",":
@@ -509,6 +595,24 @@ func (m Model) handleNormalMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			m.currentFrame++
 		}
 		
+	// Frame management
+	case "n":
+		m.addFrame(false)
+	case "N":
+		m.addFrame(true)
+	case "c":
+		m.duplicateFrames(m.currentFrame, m.currentFrame)
+	case "X":
+		m.deleteFrames(m.currentFrame, m.currentFrame)
+	case "<":
+		m.moveFrame(m.currentFrame - 1)
+	case ">":
+		m.moveFrame(m.currentFrame + 1)
+	case "[":
+		m.nudgeDelay(-frameDelayStep)
+	case "]":
+		m.nudgeDelay(frameDelayStep)
+		
 	// View controls
 	case "g":
 		m.showGrid = !m.showGrid
@@ -520,6 +624,9 @@ func (m Model) handleNormalMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		m.zoom = 1.0
 	case "z":
 		m.zenMode = !m.zenMode
+	case "o":
+		m.onionSkin = !m.onionSkin
+		m.statusMsg = m.onionStatus()
 		
 	// Command mode
 	case ":":
@@ -536,9 +643,10 @@ func (m Model) handleCommandMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		m.mode = ModeNormal
 		m.command = ""
 	case "enter":
-		m.executeCommand()
+		cmd := m.executeCommand()
 		m.mode = ModeNormal
 		m.command = ""
+		return m, cmd
 	case "backspace":
 		if len(m.command) > 0 {
 			m.command = m.command[:len(m.command)-1]
@@ -555,18 +663,17 @@ func (m Model) handleInsertMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	switch msg.String() {
 	case "esc":
 		m.mode = ModeNormal
+		m.commitEdit()
 	default:
 		if len(msg.String()) == 1 {
 			runes := []rune(msg.String())
 			if len(runes) > 0 {
 				frame := m.frames[m.currentFrame]
-				frame.Cells[m.cursor.Y][m.cursor.X] = Cell{
+				m.setCell(m.cursor.X, m.cursor.Y, Cell{
 					Char: runes[0],
 					FG:   m.fgColor,
 					BG:   m.bgColor,
-				}
-				frame.Modified = true
-				m.modified = true
+				})
 				
 				// Move cursor right
 				if m.cursor.X < frame.Width-1 {
@@ -578,10 +685,10 @@ func (m Model) handleInsertMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
-func (m *Model) executeCommand() {
+func (m *Model) executeCommand() tea.Cmd {
 	parts := strings.Fields(m.command)
 	if len(parts) == 0 {
-		return
+		return nil
 	}
 	
 	cmd := parts[0]
@@ -602,15 +709,20 @@ func (m *Model) executeCommand() {
 	
 	case "export":
 		if len(parts) < 2 {
-			m.statusMsg = "Usage: :export <file.ext> [frame]"
-			return
+			m.statusMsg = "Usage: :export <file.ext> [frame|sheet]"
+			return nil
 		}
 		filename := parts[1]
 		frameIdx := -1 // All frames
+		sheet := false
 		if len(parts) > 2 {
-			fmt.Sscanf(parts[2], "%d", &frameIdx)
+			if parts[2] == "sheet" || parts[2] == "spritesheet" {
+				sheet = true
+			} else {
+				fmt.Sscanf(parts[2], "%d", &frameIdx)
+			}
 		}
-		if err := m.exportToFile(filename, frameIdx); err != nil {
+		if err := m.exportToFile(filename, frameIdx, sheet); err != nil {
 			m.statusMsg = fmt.Sprintf("Export error: %v", err)
 		} else {
 			m.statusMsg = fmt.Sprintf("Exported to %s", filename)
@@ -642,43 +754,96 @@ func (m *Model) executeCommand() {
 			m.statusMsg = "Saved and quit"
 		}
 	
+	case "tool", "fill", "border", "text":
+		m.toolCommand(parts)
+	
+	case "paste", "pa":
+		m.pasteCommand(parts)
+	
+	case "registers", "reg":
+		m.listRegisters()
+	
+	case "clip":
+		reg := rune(unnamedRegister)
+		if len(parts) > 1 {
+			reg = []rune(parts[1])[0]
+		}
+		r := m.registers[reg]
+		if r == nil {
+			m.statusMsg = fmt.Sprintf("Register \"%c is empty", reg)
+			return nil
+		}
+		m.statusMsg = fmt.Sprintf("Copied \"%c to clipboard", reg)
+		return osc52Cmd(r.Text())
+	
+	case "layer", "la":
+		m.layerCommand(parts)
+	
+	case "frame", "fr":
+		m.frameCommand(parts)
+	
+	case "frames":
+		m.framesCommand(parts)
+	
+	case "onion":
+		m.onionCommand(parts)
+	
+	case "undo", "u":
+		m.undo(parseCount(parts, 1))
+	
+	case "redo", "red":
+		m.redo(parseCount(parts, 1))
+	
 	case "new":
 		// Create new animation
 		m.statusMsg = "New animation created"
 		// TODO: reset to blank canvas
 	
 	case "help":
-		m.statusMsg = "Commands: :save :export :import :new :quit :help"
+		m.statusMsg = "Commands: :save :export :import :tool :fill :border :text :paste :reg :clip :layer :frame :frames :onion :undo :redo :new :quit :help"
 	
 	default:
 		m.statusMsg = fmt.Sprintf("Unknown command: %s", cmd)
 	}
+	return nil
+}
+
+// parseCount reads an optional positive count argument (e.g. ":undo 5")
+func parseCount(parts []string, def int) int {
+	if len(parts) < 2 {
+		return def
+	}
+	n, err := strconv.Atoi(parts[1])
+	if err != nil || n < 1 {
+		return def
+	}
+	return n
 }
 
 // saveToFile saves the current animation to a .aart file
 func (m *Model) saveToFile(filename string) error {
-	// Import the fileformat package at the top if not already
-	aartFile := convertModelToAart(m, filename)
-	
-	// Use the fileformat package to save
-	// For now, we'll implement a simple JSON save
-	// TODO: Use fileformat.Save() once imported
-	data, err := json.Marshal(aartFile)
-	if err != nil {
-		return fmt.Errorf("failed to marshal: %w", err)
+	aartFile := m.toAartFile()
+	opts := fileformat.SaveOptions{
+		Compression:      fileformat.CompressionGzip,
+		KeyframeInterval: m.keyframeInterval,
 	}
-	
-	if err := os.WriteFile(filename, data, 0644); err != nil {
-		return fmt.Errorf("failed to write file: %w", err)
+	if err := fileformat.SaveWithOptions(filename, aartFile, opts); err != nil {
+		return err
 	}
 	
+	// Keep the saved metadata (e.g. the modified time) for the next save
+	m.document = aartFile
 	m.filename = filename
+	for _, frame := range m.frames {
+		frame.Modified = false
+	}
 	return nil
 }
 
-// exportToFile exports to various formats
-func (m *Model) exportToFile(filename string, frameIdx int) error {
-	aartFile := convertModelToAart(m, filename)
+// exportToFile exports to various formats. sheet exports a .png as a
+// sprite sheet of all frames.
+func (m *Model) exportToFile(filename string, frameIdx int, sheet bool) error {
+	aartFile := m.toAartFile()
 	
 	// Determine format from extension
 	ext := ""
@@ -695,65 +860,112 @@ func (m *Model) exportToFile(filename string, frameIdx int) error {
 		}
 		return os.WriteFile(filename, data, 0644)
 	
-	case "txt", "ans":
-		// Export as plain text
-		frame := m.frames[0]
-		if frameIdx >= 0 && frameIdx < len(m.frames) {
-			frame = m.frames[frameIdx]
-		}
-		
-		var output strings.Builder
-		for y := 0; y < frame.Height; y++ {
-			for x := 0; x < frame.Width; x++ {
-				output.WriteRune(frame.Cells[y][x].Char)
-			}
-			output.WriteRune('\n')
+	case "txt":
+		// All frames between delimiter lines, or the one asked for
+		return fileformat.Export(aartFile, filename, fileformat.ExportOptions{
+			Format:     fileformat.FormatFrames,
+			FrameIndex: frameIdx,
+			Durations:  frameIdx < 0 && len(aartFile.Frames) > 1,
+		})
+
+	case "html", "svg":
+		return fileformat.Export(aartFile, filename, fileformat.ExportOptions{
+			Format:     fileformat.ExportFormat(ext),
+			FrameIndex: frameIdx,
+			Colors:     true,
+		})
+
+	case "ansi":
+		return fileformat.Export(aartFile, filename, fileformat.ExportOptions{
+			Format:     fileformat.FormatANSI,
+			FrameIndex: frameIdx,
+			Colors:     true,
+		})
+
+	case "cast":
+		return fileformat.Export(aartFile, filename, fileformat.ExportOptions{
+			Format:     fileformat.FormatAsciicast,
+			FrameIndex: frameIdx,
+			Colors:     true,
+		})
+
+	case "ans":
+		return fileformat.Export(aartFile, filename, fileformat.ExportOptions{
+			Format:     fileformat.FormatBBS,
+			FrameIndex: frameIdx,
+			Colors:     true,
+		})
+
+	case "go":
+		return fileformat.Export(aartFile, filename, fileformat.ExportOptions{
+			Format:     fileformat.FormatGo,
+			FrameIndex: frameIdx,
+			Colors:     true,
+		})
+
+	case "gif":
+		return fileformat.Export(aartFile, filename, fileformat.ExportOptions{
+			Format:     fileformat.FormatGIF,
+			FrameIndex: frameIdx,
+			Colors:     true,
+		})
+
+	case "png":
+		format := fileformat.FormatPNG
+		if sheet {
+			format = fileformat.FormatSpriteSheet
+		} else if frameIdx < 0 {
+			frameIdx = m.currentFrame
 		}
-		
-		return os.WriteFile(filename, []byte(output.String()), 0644)
+		return fileformat.Export(aartFile, filename, fileformat.ExportOptions{
+			Format:     format,
+			FrameIndex: frameIdx,
+			Colors:     true,
+		})
 	
 	default:
 		return fmt.Errorf("unsupported export format: %s", ext)
 	}
 }
 
-// convertModelToAart converts the model to AartFile structure
-func convertModelToAart(m *Model, filename string) map[string]interface{} {
-	// Create a simplified version for now
-	// TODO: Use actual fileformat.AartFile once imported
-	frames := make([]map[string]interface{}, len(m.frames))
-	for i, frame := range m.frames {
-		cells := make([][]map[string]string, frame.Height)
-		for y := 0; y < frame.Height; y++ {
-			cells[y] = make([]map[string]string, frame.Width)
-			for x := 0; x < frame.Width; x++ {
-				cells[y][x] = map[string]string{
-					"char": string(frame.Cells[y][x].Char),
-					"fg":   frame.Cells[y][x].FG,
-					"bg":   frame.Cells[y][x].BG,
-				}
+// toAartFile converts the model to the file format. Fields the editor does
+// not edit (metadata, palette, audio...) come from the loaded document.
+func (m *Model) toAartFile() *fileformat.AartFile {
+	m.loadAllFrames()
+	aart := *m.document
+	if aart.Metadata.Title == "" {
+		aart.Metadata.Title = strings.TrimSuffix(filepath.Base(m.filename), filepath.Ext(m.filename))
+	}
+	aart.Canvas = fileformat.Canvas{Width: m.frames[0].Width, Height: m.frames[0].Height}
+	aart.Layers = fileLayers(m.layers)
+	
+	toGrid := func(grid [][]Cell) [][]fileformat.Cell {
+		cells := make([][]fileformat.Cell, len(grid))
+		for y := range grid {
+			cells[y] = make([]fileformat.Cell, len(grid[y]))
+			for x, cell := range grid[y] {
+				cells[y][x] = toFileCell(cell)
 			}
 		}
-		frames[i] = map[string]interface{}{
-			"index": i,
-			"duration": frame.Delay,
-			"cells": cells,
-		}
+		return cells
 	}
 	
-	return map[string]interface{}{
-		"version": "1.0",
-		"metadata": map[string]interface{}{
-			"title": filename,
-			"created": time.Now().Format(time.RFC3339),
-			"modified": time.Now().Format(time.RFC3339),
-		},
-		"canvas": map[string]int{
-			"width":  m.frames[0].Width,
-			"height": m.frames[0].Height,
-		},
-		"frames": frames,
+	aart.Frames = make([]fileformat.Frame, len(m.frames))
+	for i, frame := range m.frames {
+		layers := make([]fileformat.FrameLayer, len(frame.Layers))
+		for l, grid := range frame.Layers {
+			layers[l] = fileformat.FrameLayer{Cells: toGrid(grid)}
+		}
+		aart.Frames[i] = fileformat.Frame{
+			Index:    i,
+			Duration: frame.Delay,
+			Name:     frame.Name,
+			Cells:    toGrid(m.composite(frame)),
+			Layers:   layers,
+		}
 	}
+	
+	return &aart
 }
 
 func (m Model) View() string {
@@ -791,20 +1003,24 @@ func (m Model) View() string {
 
 func (m Model) renderCanvasOnly() string {
 	frame := m.frames[m.currentFrame]
+	cells := m.composite(frame)
+	ghosts := m.onionGhosts(cells)
 	var b strings.Builder
 	
 	// Just the canvas content, no borders
 	for y := 0; y < min(frame.Height, m.height); y++ {
 		for x := 0; x < min(frame.Width, m.width); x++ {
-			cell := frame.Cells[y][x]
+			cell := cells[y][x]
 			
 			// Show cursor in zen mode too
 			if x == m.cursor.X && y == m.cursor.Y && m.mode != ModeCommand {
 				b.WriteString(lipgloss.NewStyle().
 					Foreground(lipgloss.Color("11")).
 					Render("┃"))
+			} else if ghosts != nil && ghosts[y][x].char != 0 {
+				b.WriteString(m.ghostStyle(ghosts[y][x]).Render(string(ghosts[y][x].char)))
 			} else {
-				b.WriteString(string(cell.Char))
+				b.WriteString(cellText(cell))
 			}
 		}
 		b.WriteString("\n")
@@ -857,6 +1073,8 @@ func (m Model) renderCanvas(height int) string {
 	}
 	
 	frame := m.frames[m.currentFrame]
+	cells := m.composite(frame)
+	ghosts := m.onionGhosts(cells)
 	
 	// Top border
 	border := lipgloss.NewStyle().Foreground(lipgloss.Color("8"))
@@ -867,21 +1085,31 @@ func (m Model) renderCanvas(height int) string {
 	}
 	b.WriteString(line + "\n")
 	
+	preview := m.toolPreview()
+	previewStyle := lipgloss.NewStyle().Foreground(m.theme.AccentSecondary)
+	selectedStyle := lipgloss.NewStyle().Background(m.theme.Selection)
+	
 	// Canvas content
 	for y := 0; y < height-2; y++ {
 		lineContent := border.Render("│")
 		
 		for x := 0; x < canvasWidth-2; x++ {
 			if x < frame.Width && y < frame.Height {
-				cell := frame.Cells[y][x]
+				cell := cells[y][x]
 				
 				// Show cursor
 				if x == m.cursor.X && y == m.cursor.Y && m.mode != ModeCommand {
 					lineContent += lipgloss.NewStyle().
 						Foreground(lipgloss.Color("11")).
 						Render("┃")
+				} else if r, ok := preview[Pos{x, y}]; ok {
+					lineContent += previewStyle.Render(string(r))
+				} else if m.isSelected(x, y) {
+					lineContent += selectedStyle.Render(cell.glyph())
+				} else if ghosts != nil && ghosts[y][x].char != 0 {
+					lineContent += m.ghostStyle(ghosts[y][x]).Render(string(ghosts[y][x].char))
 				} else {
-					lineContent += string(cell.Char)
+					lineContent += cellText(cell)
 				}
 			} else {
 				lineContent += " "
@@ -979,10 +1207,10 @@ func (m Model) renderExpandedWheel(line, totalHeight int) string {
 	case WheelTools:
 		content = []string{
 			"╭─ TOOLS ────────────╮",
-			"│ ● [p] pencil       │",
+			"│   [p] pencil       │",
 			"│   [f] fill         │",
 			"│   [s] select       │",
-			"│   [l] line         │",
+			"│   [L] line         │",
 			"│   [b] box          │",
 			"│   [t] text         │",
 			"│   [e] eyedropper   │",
@@ -1018,12 +1246,16 @@ func (m Model) renderExpandedWheel(line, totalHeight int) string {
 		content = []string{
 			"╭─ HELP ─────────────╮",
 			"│ hjkl    move       │",
-			"│ d       draw       │",
+			"│ d       use tool   │",
 			"│ i       insert     │",
+			"│ u/^r    undo/redo  │",
 			"│ space   play/pause │",
 			"│ ,.      seek       │",
+			"│ n/c/X   new/dup/del│",
+			"│ </> []  move/delay │",
 			"│ +/-     zoom       │",
 			"│ g       grid       │",
+			"│ o       onion skin │",
 			"│ ctrl-j/k  wheel    │",
 			"│ enter   expand     │",
 			"│ esc     collapse   │",
@@ -1032,6 +1264,28 @@ func (m Model) renderExpandedWheel(line, totalHeight int) string {
 			"╰────────────────────╯",
 		}
 		
+	case WheelLayers:
+		content = []string{"╭─ LAYERS ───────────╮"}
+		// Top layer first, like the stack is drawn
+		for i := len(m.layers) - 1; i >= 0; i-- {
+			l := m.layers[i]
+			mark := " "
+			if i == m.currentLayer {
+				mark = "●"
+			}
+			vis := "◉"
+			if !l.Visible {
+				vis = "○"
+			}
+			content = append(content, fmt.Sprintf("│ %s%s %-16s│", mark, vis, truncate(fmt.Sprintf("%d %s", i+1, l.Name), 16)))
+		}
+		content = append(content,
+			"│                    │",
+			"│  :layer add/del    │",
+			"│  :layer move/merge │",
+			"╰────────────────────╯",
+		)
+		
 	default:
 		content = []string{
 			"╭─ " + wheelNames[m.wheel.Selected] + " ──────────╮",
@@ -0,0 +1,105 @@
+package ui
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// maxOnionFrames caps how many frames on each side the onion skin shows
+const maxOnionFrames = 5
+
+// ghost is a character of an adjacent frame shown through a blank cell
+type ghost struct {
+	char rune
+	dist int  // frames away from the current one
+	next bool // from a following frame
+}
+
+// onionGhosts returns, for every blank cell of the current frame, the
+// character of the nearest adjacent frame that draws something there.
+// Previous frames win over next frames at the same distance.
+func (m Model) onionGhosts(current [][]Cell) [][]ghost {
+	if !m.onionSkin || m.playing || (m.onionPrev == 0 && m.onionNext == 0) {
+		return nil
+	}
+
+	frame := m.frames[m.currentFrame]
+	ghosts := make([][]ghost, frame.Height)
+	for y := range ghosts {
+		ghosts[y] = make([]ghost, frame.Width)
+	}
+
+	apply := func(idx, dist int, next bool) {
+		if idx < 0 || idx >= len(m.frames) {
+			return
+		}
+		other := m.frames[idx]
+		cells := m.composite(other)
+		for y := 0; y < frame.Height && y < other.Height; y++ {
+			for x := 0; x < frame.Width && x < other.Width; x++ {
+				if ghosts[y][x].char != 0 || current[y][x].Char != ' ' {
+					continue
+				}
+				if c := cells[y][x].Char; c != ' ' && c != 0 {
+					ghosts[y][x] = ghost{char: c, dist: dist, next: next}
+				}
+			}
+		}
+	}
+
+	// Nearest frames first so they take precedence
+	for d := 1; d <= max(m.onionPrev, m.onionNext); d++ {
+		if d <= m.onionPrev {
+			apply(m.currentFrame-d, d, false)
+		}
+		if d <= m.onionNext {
+			apply(m.currentFrame+d, d, true)
+		}
+	}
+	return ghosts
+}
+
+// ghostStyle returns the dimmed style for a ghost; farther frames are fainter
+func (m Model) ghostStyle(g ghost) lipgloss.Style {
+	color := m.theme.OnionPrev
+	if g.next {
+		color = m.theme.OnionNext
+	}
+	return lipgloss.NewStyle().Foreground(color).Faint(g.dist > 1)
+}
+
+// onionCommand handles :onion [on|off|PREV NEXT]
+func (m *Model) onionCommand(parts []string) {
+	switch {
+	case len(parts) == 1:
+		m.onionSkin = !m.onionSkin
+	case len(parts) == 2 && parts[1] == "on":
+		m.onionSkin = true
+	case len(parts) == 2 && parts[1] == "off":
+		m.onionSkin = false
+	case len(parts) == 3:
+		prev, err1 := strconv.Atoi(parts[1])
+		next, err2 := strconv.Atoi(parts[2])
+		if err1 != nil || err2 != nil || prev < 0 || next < 0 {
+			m.statusMsg = "Usage: :onion [on|off|PREV NEXT]"
+			return
+		}
+		m.onionPrev = min(prev, maxOnionFrames)
+		m.onionNext = min(next, maxOnionFrames)
+		m.onionSkin = true
+	default:
+		m.statusMsg = "Usage: :onion [on|off|PREV NEXT]"
+		return
+	}
+	m.statusMsg = m.onionStatus()
+}
+
+// onionStatus describes the onion skin setting
+func (m Model) onionStatus() string {
+	if !m.onionSkin {
+		return "Onion skin off"
+	}
+	return fmt.Sprintf("Onion skin: %d previous, %d next", m.onionPrev, m.onionNext)
+}
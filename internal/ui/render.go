@@ -2,6 +2,7 @@ package ui
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 
 	"github.com/charmbracelet/lipgloss"
@@ -9,6 +10,18 @@ import (
 
 // Beautiful themed render functions
 
+// cellText renders a canvas cell's character with its text attributes
+func cellText(cell Cell) string {
+	if !cell.Bold && !cell.Italic && !cell.Underline {
+		return cell.glyph()
+	}
+	return lipgloss.NewStyle().
+		Bold(cell.Bold).
+		Italic(cell.Italic).
+		Underline(cell.Underline).
+		Render(cell.glyph())
+}
+
 func (m Model) renderStatusBar() string {
 	// Compact status bar without emoji clutter
 	
@@ -39,8 +52,7 @@ func (m Model) renderStatusBar() string {
 	}
 	
 	// Tool info - clean without icon
-	toolName := toolNames[m.selectedTool]
-	toolInfo := fmt.Sprintf("%s", toolName)
+	toolInfo := m.toolDetail()
 	
 	// Layer info
 	layerInfo := fmt.Sprintf("layer %d/%d", 
@@ -61,6 +73,11 @@ func (m Model) renderStatusBar() string {
 		layerInfo,
 	}
 	
+	// Onion skin
+	if m.onionSkin {
+		sections = append(sections, fmt.Sprintf("onion -%d/+%d", m.onionPrev, m.onionNext))
+	}
+	
 	content := strings.Join(sections, div)
 	
 	return m.styles.StatusBar.Render(content)
@@ -80,8 +97,9 @@ func (m Model) renderTimeline() string {
 	// Simplified top border - no title, just clean line
 	b.WriteString(borderStyle.Render("├" + strings.Repeat("─", lineWidth) + "┤\n│ "))
 	
-	// Frame indicators - scrolling window centered on current frame
-	maxFrames := 30
+	// Frame indicators with their durations - scrolling window centered on
+	// current frame. Each entry is a marker, the delay in ms and a space.
+	maxFrames := (lineWidth - 16) / 6
 	totalFrames := len(m.frames)
 	
 	// Calculate visible frame window
@@ -145,6 +163,11 @@ func (m Model) renderTimeline() string {
 		}
 		
 		b.WriteString(frameStyle.Render(frameText))
+		delayStyle := lipgloss.NewStyle().Foreground(m.theme.FgMuted)
+		if i == m.currentFrame {
+			delayStyle = lipgloss.NewStyle().Foreground(m.theme.FgSecondary)
+		}
+		b.WriteString(delayStyle.Render(strconv.Itoa(m.frameDelay(i))))
 		if i < endFrame-1 {
 			b.WriteString(" ")
 		}
@@ -200,7 +223,7 @@ func (m Model) renderTimeline() string {
 	
 	// Frame timing
 	if len(m.frames) > m.currentFrame {
-		duration := 1000 / m.fps
+		duration := m.frameDelay(m.currentFrame)
 		timingStyle := lipgloss.NewStyle().
 			Foreground(m.theme.FgSecondary)
 		b.WriteString(timingStyle.Render(fmt.Sprintf("%dms", duration)))
@@ -261,6 +284,9 @@ func (m Model) renderBottomStatus() string {
 	case ModeInsert:
 		modeStyle = m.styles.InsertMode
 		modeText = "INSERT"
+	case ModeVisual:
+		modeStyle = m.styles.VisualMode
+		modeText = selectionModeNames[m.selection.Kind]
 	case ModeCommand:
 		modeStyle = m.styles.CommandMode
 		modeText = "COMMAND"
@@ -282,5 +308,18 @@ func (m Model) renderBottomStatus() string {
 	
 	b.WriteString(modeStyle.Render(fmt.Sprintf(" %s ", modeText)))
 	
+	// Pending register prefix and last status message
+	if m.pendingRegister != 0 {
+		b.WriteString(lipgloss.NewStyle().
+			Foreground(m.theme.AccentSecondary).
+			Render(fmt.Sprintf(" \"%c", m.pendingRegister)))
+	}
+	if m.statusMsg != "" {
+		b.WriteString(" ")
+		b.WriteString(lipgloss.NewStyle().
+			Foreground(m.theme.FgSecondary).
+			Render(m.statusMsg))
+	}
+	
 	return b.String()
 }
@@ -0,0 +1,409 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aymanbagabas/go-osc52/v2"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// SelectionKind is the shape of a visual selection
+type SelectionKind int
+
+const (
+	SelectChar  SelectionKind = iota // v: stream of cells in reading order
+	SelectLine                       // V: whole rows
+	SelectBlock                      // ctrl+v: rectangle
+)
+
+var selectionModeNames = []string{"VISUAL", "V-LINE", "V-BLOCK"}
+
+// Selection is a region of the canvas spanned by an anchor and the cursor
+type Selection struct {
+	Kind   SelectionKind
+	Anchor Pos
+	Cursor Pos
+}
+
+// Bounds returns the bounding rectangle of the selection, inclusive
+func (s Selection) Bounds(width int) (x0, y0, x1, y1 int) {
+	y0, y1 = min(s.Anchor.Y, s.Cursor.Y), max(s.Anchor.Y, s.Cursor.Y)
+	switch s.Kind {
+	case SelectBlock:
+		x0, x1 = min(s.Anchor.X, s.Cursor.X), max(s.Anchor.X, s.Cursor.X)
+	case SelectChar:
+		if y0 == y1 {
+			x0, x1 = min(s.Anchor.X, s.Cursor.X), max(s.Anchor.X, s.Cursor.X)
+		} else {
+			x0, x1 = 0, width-1
+		}
+	default:
+		x0, x1 = 0, width-1
+	}
+	return x0, y0, x1, y1
+}
+
+// Contains reports whether x,y is inside the selection
+func (s Selection) Contains(x, y, width int) bool {
+	x0, y0, x1, y1 := s.Bounds(width)
+	if x < x0 || x > x1 || y < y0 || y > y1 {
+		return false
+	}
+	if s.Kind != SelectChar || y0 == y1 {
+		return true
+	}
+
+	// Stream selection: partial first and last rows
+	start, end := s.Anchor, s.Cursor
+	if end.Y < start.Y {
+		start, end = end, start
+	}
+	if y == start.Y {
+		return x >= start.X
+	}
+	if y == end.Y {
+		return x <= end.X
+	}
+	return true
+}
+
+// Register holds yanked cells. Mask marks which cells belong to the
+// selection, so stream selections paste back with their shape.
+type Register struct {
+	Kind   SelectionKind
+	Width  int
+	Height int
+	Cells  [][]Cell
+	Mask   [][]bool
+}
+
+// Text returns the register contents as plain text
+func (r *Register) Text() string {
+	lines := make([]string, r.Height)
+	for y := 0; y < r.Height; y++ {
+		var b strings.Builder
+		for x := 0; x < r.Width; x++ {
+			if r.Mask[y][x] {
+				b.WriteString(r.Cells[y][x].glyph())
+			} else {
+				b.WriteRune(' ')
+			}
+		}
+		lines[y] = strings.TrimRight(b.String(), " ")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Registers are shared by every frame, so a region yanked in one frame can
+// be pasted into others
+type Registers map[rune]*Register
+
+const (
+	unnamedRegister   = '"'
+	clipboardRegister = '+'
+)
+
+// startVisual enters visual mode with the given selection kind
+func (m *Model) startVisual(kind SelectionKind) {
+	m.mode = ModeVisual
+	m.selection = &Selection{Kind: kind, Anchor: m.cursor, Cursor: m.cursor}
+}
+
+// isSelected reports whether x,y is highlighted as selected
+func (m Model) isSelected(x, y int) bool {
+	if m.selection == nil {
+		return false
+	}
+	return m.selection.Contains(x, y, m.frames[m.currentFrame].Width)
+}
+
+// yankSelection copies the selection of the current frame into a register
+func (m *Model) yankSelection(reg rune) *Register {
+	frame := m.frames[m.currentFrame]
+	sel := *m.selection
+	x0, y0, x1, y1 := sel.Bounds(frame.Width)
+	x1 = min(x1, frame.Width-1)
+	y1 = min(y1, frame.Height-1)
+
+	r := &Register{Kind: sel.Kind, Width: x1 - x0 + 1, Height: y1 - y0 + 1}
+	r.Cells = make([][]Cell, r.Height)
+	r.Mask = make([][]bool, r.Height)
+	for y := 0; y < r.Height; y++ {
+		r.Cells[y] = make([]Cell, r.Width)
+		r.Mask[y] = make([]bool, r.Width)
+		for x := 0; x < r.Width; x++ {
+			r.Cells[y][x] = frame.Layers[m.currentLayer][y0+y][x0+x]
+			r.Mask[y][x] = sel.Contains(x0+x, y0+y, frame.Width)
+		}
+	}
+
+	m.registers[unnamedRegister] = r
+	if reg != unnamedRegister {
+		m.registers[reg] = r
+	}
+	return r
+}
+
+// clearSelection blanks every selected cell of the current frame
+func (m *Model) clearSelection() {
+	frame := m.frames[m.currentFrame]
+	blank := m.blankCell()
+	x0, y0, x1, y1 := m.selection.Bounds(frame.Width)
+	for y := y0; y <= y1; y++ {
+		for x := x0; x <= x1; x++ {
+			if m.selection.Contains(x, y, frame.Width) {
+				m.setCell(x, y, blank)
+			}
+		}
+	}
+}
+
+// pasteRegister stamps a register with its top-left corner at x,y of the
+// given frame. In transparent mode spaces are skipped.
+func (m *Model) pasteRegister(frameIdx int, r *Register, x, y int, transparent bool) {
+	for dy := 0; dy < r.Height; dy++ {
+		for dx := 0; dx < r.Width; dx++ {
+			if !r.Mask[dy][dx] {
+				continue
+			}
+			cell := r.Cells[dy][dx]
+			if transparent && cell.Char == ' ' {
+				continue
+			}
+			m.setFrameCell(frameIdx, x+dx, y+dy, cell)
+		}
+	}
+}
+
+// takeRegister returns the pending register name and resets it
+func (m *Model) takeRegister() rune {
+	reg := m.pendingRegister
+	m.pendingRegister = 0
+	if reg == 0 {
+		reg = unnamedRegister
+	}
+	return reg
+}
+
+func (m Model) handleVisualMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	frame := m.frames[m.currentFrame]
+	key := msg.String()
+
+	// Second key of a "x register prefix
+	if m.awaitRegister {
+		m.awaitRegister = false
+		if r := []rune(key); len(r) == 1 {
+			m.pendingRegister = r[0]
+		}
+		return m, nil
+	}
+
+	switch key {
+	case "esc":
+		m.mode = ModeNormal
+		m.selection = nil
+		m.pendingRegister = 0
+
+	case "h", "left":
+		if m.cursor.X > 0 {
+			m.cursor.X--
+		}
+	case "l", "right":
+		if m.cursor.X < frame.Width-1 {
+			m.cursor.X++
+		}
+	case "k", "up":
+		if m.cursor.Y > 0 {
+			m.cursor.Y--
+		}
+	case "j", "down":
+		if m.cursor.Y < frame.Height-1 {
+			m.cursor.Y++
+		}
+	case "0":
+		m.cursor.X = 0
+	case "$":
+		m.cursor.X = frame.Width - 1
+
+	case "o":
+		// Jump to the other end of the selection
+		m.selection.Anchor, m.cursor = m.cursor, m.selection.Anchor
+
+	case "v", "V", "ctrl+v":
+		kind := map[string]SelectionKind{"v": SelectChar, "V": SelectLine, "ctrl+v": SelectBlock}[key]
+		if m.selection.Kind == kind {
+			m.mode = ModeNormal
+			m.selection = nil
+			return m, nil
+		}
+		m.selection.Kind = kind
+
+	case "\"":
+		m.awaitRegister = true
+		return m, nil
+
+	case "y":
+		reg := m.takeRegister()
+		r := m.yankSelection(reg)
+		m.mode = ModeNormal
+		m.selection = nil
+		m.statusMsg = fmt.Sprintf("Yanked %dx%d into \"%c", r.Width, r.Height, reg)
+		if reg == clipboardRegister {
+			return m, osc52Cmd(r.Text())
+		}
+
+	case "d", "x":
+		reg := m.takeRegister()
+		r := m.yankSelection(reg)
+		m.beginEdit("delete")
+		m.clearSelection()
+		m.commitEdit()
+		m.mode = ModeNormal
+		m.selection = nil
+		if reg == clipboardRegister {
+			return m, osc52Cmd(r.Text())
+		}
+
+	case "m":
+		// Keep the selection and pick it up with the move tool
+		m.mode = ModeNormal
+		m.selectTool(ToolMove)
+		anchor := m.cursor
+		m.toolAnchor = &anchor
+		m.statusMsg = "move: move the cursor and press d to drop the selection"
+		return m, nil
+	}
+
+	if m.selection != nil {
+		m.selection.Cursor = m.cursor
+	}
+	return m, nil
+}
+
+// moveSelection moves the selected cells by dx,dy. The vacated cells are
+// blanked and the selection follows its contents.
+func (m *Model) moveSelection(dx, dy int) {
+	frame := m.frames[m.currentFrame]
+	sel := *m.selection
+	x0, y0, x1, y1 := sel.Bounds(frame.Width)
+
+	type moved struct {
+		pos  Pos
+		cell Cell
+	}
+	var cells []moved
+	for y := y0; y <= min(y1, frame.Height-1); y++ {
+		for x := x0; x <= min(x1, frame.Width-1); x++ {
+			if sel.Contains(x, y, frame.Width) {
+				cells = append(cells, moved{Pos{x, y}, frame.Layers[m.currentLayer][y][x]})
+			}
+		}
+	}
+
+	m.clearSelection()
+	for _, c := range cells {
+		m.setCell(c.pos.X+dx, c.pos.Y+dy, c.cell)
+	}
+
+	m.selection.Anchor.X += dx
+	m.selection.Anchor.Y += dy
+	m.selection.Cursor.X += dx
+	m.selection.Cursor.Y += dy
+}
+
+// pasteCommand handles :paste [register] [frames] [t]. Frames are a
+// 1-based index or range, e.g. ":paste a 4-40 t" stamps register a into
+// frames 4 to 40 with transparency.
+func (m *Model) pasteCommand(parts []string) {
+	reg := rune(unnamedRegister)
+	from, to := m.currentFrame, m.currentFrame
+	transparent := false
+
+	for _, arg := range parts[1:] {
+		switch {
+		case arg == "t" || arg == "transparent":
+			transparent = true
+		case arg[0] >= '0' && arg[0] <= '9':
+			a, b, err := parseFrameRange(arg, len(m.frames))
+			if err != nil {
+				m.statusMsg = err.Error()
+				return
+			}
+			from, to = a, b
+		case len([]rune(arg)) == 1:
+			reg = []rune(arg)[0]
+		default:
+			m.statusMsg = "Usage: :paste [register] [from-to] [t]"
+			return
+		}
+	}
+
+	r := m.registers[reg]
+	if r == nil {
+		m.statusMsg = fmt.Sprintf("Register \"%c is empty", reg)
+		return
+	}
+
+	m.beginEdit("paste")
+	for i := from; i <= to; i++ {
+		m.pasteRegister(i, r, m.cursor.X, m.cursor.Y, transparent)
+	}
+	m.commitEdit()
+	m.statusMsg = fmt.Sprintf("Pasted \"%c into %d frame(s)", reg, to-from+1)
+}
+
+// listRegisters shows the non-empty registers in the status line
+func (m *Model) listRegisters() {
+	if len(m.registers) == 0 {
+		m.statusMsg = "No registers"
+		return
+	}
+	names := make([]string, 0, len(m.registers))
+	for name, r := range m.registers {
+		names = append(names, fmt.Sprintf("\"%c %dx%d", name, r.Width, r.Height))
+	}
+	sort.Strings(names)
+	m.statusMsg = strings.Join(names, "  ")
+}
+
+// parseFrameRange parses a 1-based "N" or "N-M" range into 0-based indices
+func parseFrameRange(s string, count int) (int, int, error) {
+	parts := strings.SplitN(s, "-", 2)
+	a, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid frame range: %s", s)
+	}
+	b := a
+	if len(parts) == 2 {
+		if b, err = strconv.Atoi(parts[1]); err != nil {
+			return 0, 0, fmt.Errorf("invalid frame range: %s", s)
+		}
+	}
+	if a > b {
+		a, b = b, a
+	}
+	if a < 1 || b > count {
+		return 0, 0, fmt.Errorf("frame range %s out of bounds (1-%d)", s, count)
+	}
+	return a - 1, b - 1, nil
+}
+
+// osc52Cmd copies text to the system clipboard with an OSC 52 escape
+// sequence, which most terminal emulators understand. Inside tmux or
+// screen the sequence is wrapped so it reaches the outer terminal.
+func osc52Cmd(text string) tea.Cmd {
+	return func() tea.Msg {
+		seq := osc52.New(text)
+		if os.Getenv("TMUX") != "" {
+			seq = seq.Tmux()
+		} else if strings.HasPrefix(os.Getenv("TERM"), "screen") {
+			seq = seq.Screen()
+		}
+		seq.WriteTo(os.Stderr)
+		return nil
+	}
+}
@@ -12,6 +12,7 @@ import (
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/mlamkadm/aart/internal/config"
+	"github.com/mlamkadm/aart/internal/converter"
 	"github.com/mlamkadm/aart/internal/fileformat"
 )
 
@@ -77,14 +78,14 @@ func NewStartupPage(cfg *config.Config) StartupPage {
 		{
 			Icon:        "📂",
 			Title:       "Open File",
-			Description: "Open an existing .aart file",
+			Description: "Open a .aart file, or import an image",
 			Action:      "open",
 			Shortcut:    "o",
 		},
 		{
 			Icon:        "🎬",
-			Title:       "Import GIF",
-			Description: "Convert GIF to ASCII animation",
+			Title:       "Import GIF/Image",
+			Description: "Convert a GIF, PNG, JPEG or BMP to ASCII",
 			Action:      "import",
 			Shortcut:    "i",
 		},
@@ -288,10 +289,10 @@ func (s StartupPage) handleAction() (tea.Model, tea.Cmd) {
 		return model, model.Init()
 	case "open":
 		// Open file picker
-		picker := NewFilePicker(s.config, "📂 Open File", ".aart", s)
+		picker := NewFilePicker(s.config, "📂 Open File", ".aart,.aartz,"+strings.Join(converter.ImageExtensions, ","), s)
 		return picker, picker.Init()
 	case "import":
-		// Show GIF import dialog
+		// Show GIF/image import dialog
 		importer := NewImportGIFScreen(s.config, s)
 		return importer, importer.Init()
 	case "quit":
@@ -340,6 +341,7 @@ func (s StartupPage) editConfigCmd() tea.Cmd {
 		// Reload config after editing
 		newCfg, _ := config.Load()
 		if newCfg != nil {
+			newCfg.KeyframeIntervalOverride = s.config.KeyframeIntervalOverride
 			*s.config = *newCfg
 		}
 		return editorFinishedMsg{}
@@ -647,7 +649,7 @@ func (s StartupPage) renderRecentPanel() string {
 			"No recent files yet",
 			"",
 			lipgloss.NewStyle().Foreground(s.theme.AccentSecondary).Render("Press 'n' to create"),
-			lipgloss.NewStyle().Foreground(s.theme.AccentSecondary).Render("Press 'i' to import GIF"),
+			lipgloss.NewStyle().Foreground(s.theme.AccentSecondary).Render("Press 'i' to import a GIF or image"),
 		)
 		
 		items = append(items, emptyBox.Render(emptyContent))
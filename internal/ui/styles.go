@@ -36,6 +36,7 @@ type Styles struct {
 	CommandMode  lipgloss.Style
 	InsertMode   lipgloss.Style
 	NormalMode   lipgloss.Style
+	VisualMode   lipgloss.Style
 	
 	// Wheel
 	WheelSection       lipgloss.Style
@@ -147,6 +148,12 @@ func NewStyles(theme Theme) Styles {
 		Bold(true).
 		Padding(0, 1)
 	
+	s.VisualMode = lipgloss.NewStyle().
+		Background(theme.AccentSecondary).
+		Foreground(theme.BgPrimary).
+		Bold(true).
+		Padding(0, 1)
+	
 	// Wheel - Radial menu styling
 	s.WheelSection = lipgloss.NewStyle().
 		Foreground(theme.FgSecondary).
@@ -39,6 +39,10 @@ type Theme struct {
 	TimelineInactive lipgloss.Color
 	PlayheadColor    lipgloss.Color
 	
+	// Onion skin ghosts of previous and next frames
+	OnionPrev lipgloss.Color
+	OnionNext lipgloss.Color
+	
 	// Status
 	StatusBg lipgloss.Color
 	StatusFg lipgloss.Color
@@ -70,6 +74,8 @@ var (
 		TimelineActive:   lipgloss.Color("#88C0D0"),
 		TimelineInactive: lipgloss.Color("#4C566A"),
 		PlayheadColor:    lipgloss.Color("#A3BE8C"),
+		OnionPrev:        lipgloss.Color("#8F5A5F"),
+		OnionNext:        lipgloss.Color("#4F6F8F"),
 		StatusBg:         lipgloss.Color("#3B4252"),
 		StatusFg:         lipgloss.Color("#ECEFF4"),
 	}
@@ -98,6 +104,8 @@ var (
 		TimelineActive:   lipgloss.Color("#BD93F9"),
 		TimelineInactive: lipgloss.Color("#44475A"),
 		PlayheadColor:    lipgloss.Color("#50FA7B"),
+		OnionPrev:        lipgloss.Color("#8A4A6E"),
+		OnionNext:        lipgloss.Color("#4A6A8A"),
 		StatusBg:         lipgloss.Color("#44475A"),
 		StatusFg:         lipgloss.Color("#F8F8F2"),
 	}
@@ -126,6 +134,8 @@ var (
 		TimelineActive:   lipgloss.Color("#7AA2F7"),
 		TimelineInactive: lipgloss.Color("#414868"),
 		PlayheadColor:    lipgloss.Color("#9ECE6A"),
+		OnionPrev:        lipgloss.Color("#7A4A5A"),
+		OnionNext:        lipgloss.Color("#3D5A80"),
 		StatusBg:         lipgloss.Color("#24283B"),
 		StatusFg:         lipgloss.Color("#C0CAF5"),
 	}
@@ -154,6 +164,8 @@ var (
 		TimelineActive:   lipgloss.Color("#83A598"),
 		TimelineInactive: lipgloss.Color("#504945"),
 		PlayheadColor:    lipgloss.Color("#B8BB26"),
+		OnionPrev:        lipgloss.Color("#7C3A32"),
+		OnionNext:        lipgloss.Color("#45707A"),
 		StatusBg:         lipgloss.Color("#3C3836"),
 		StatusFg:         lipgloss.Color("#EBDBB2"),
 	}
@@ -182,6 +194,8 @@ var (
 		TimelineActive:   lipgloss.Color("#89B4FA"),
 		TimelineInactive: lipgloss.Color("#45475A"),
 		PlayheadColor:    lipgloss.Color("#A6E3A1"),
+		OnionPrev:        lipgloss.Color("#8C5A6E"),
+		OnionNext:        lipgloss.Color("#4E6A8C"),
 		StatusBg:         lipgloss.Color("#313244"),
 		StatusFg:         lipgloss.Color("#CDD6F4"),
 	}
@@ -210,6 +224,8 @@ var (
 		TimelineActive:   lipgloss.Color("#6699CC"),
 		TimelineInactive: lipgloss.Color("#4F5B66"),
 		PlayheadColor:    lipgloss.Color("#99C794"),
+		OnionPrev:        lipgloss.Color("#8A4E52"),
+		OnionNext:        lipgloss.Color("#4A6E8E"),
 		StatusBg:         lipgloss.Color("#343D46"),
 		StatusFg:         lipgloss.Color("#C0C5CE"),
 	}
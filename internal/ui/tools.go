@@ -0,0 +1,409 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FillMatch selects which cell attribute the flood fill compares
+type FillMatch int
+
+const (
+	MatchChar FillMatch = iota
+	MatchFG
+	MatchBG
+)
+
+var fillMatchNames = []string{"char", "fg", "bg"}
+
+// BorderSet holds the characters used by the box tool
+type BorderSet struct {
+	Name                    string
+	TopLeft, TopRight       rune
+	BottomLeft, BottomRight rune
+	Horizontal, Vertical    rune
+}
+
+// borderSets are the box styles selectable with :border
+var borderSets = []BorderSet{
+	{Name: "ascii", TopLeft: '+', TopRight: '+', BottomLeft: '+', BottomRight: '+', Horizontal: '-', Vertical: '|'},
+	{Name: "single", TopLeft: '┌', TopRight: '┐', BottomLeft: '└', BottomRight: '┘', Horizontal: '─', Vertical: '│'},
+	{Name: "double", TopLeft: '╔', TopRight: '╗', BottomLeft: '╚', BottomRight: '╝', Horizontal: '═', Vertical: '║'},
+	{Name: "rounded", TopLeft: '╭', TopRight: '╮', BottomLeft: '╰', BottomRight: '╯', Horizontal: '─', Vertical: '│'},
+	{Name: "heavy", TopLeft: '┏', TopRight: '┓', BottomLeft: '┗', BottomRight: '┛', Horizontal: '━', Vertical: '┃'},
+}
+
+// toolByName returns the tool with the given name or key
+func toolByName(name string) (Tool, bool) {
+	for i, n := range toolNames {
+		if n == name || toolKeys[i] == name {
+			return Tool(i), true
+		}
+	}
+	return ToolPencil, false
+}
+
+// selectTool switches the active tool and drops any pending anchor
+func (m *Model) selectTool(t Tool) {
+	m.selectedTool = t
+	m.toolAnchor = nil
+	switch t {
+	case ToolLine, ToolBox, ToolMove:
+		m.statusMsg = fmt.Sprintf("%s: press d to set the start point", toolNames[t])
+	case ToolText:
+		if m.stampText == "" {
+			m.statusMsg = "text: set the stamp with :text <string>"
+		}
+	}
+}
+
+// brushCell returns the cell painted by the drawing tools
+func (m *Model) brushCell() Cell {
+	return Cell{Char: m.fgChar, FG: m.fgColor, BG: m.bgColor}
+}
+
+// applyTool runs the selected tool at the cursor
+func (m *Model) applyTool() {
+	switch m.selectedTool {
+	case ToolPencil:
+		m.beginEdit("draw")
+		m.setCell(m.cursor.X, m.cursor.Y, m.brushCell())
+		m.commitEdit()
+
+	case ToolFill:
+		m.beginEdit("fill")
+		n := m.floodFill(m.cursor.X, m.cursor.Y, m.brushCell())
+		m.commitEdit()
+		m.statusMsg = fmt.Sprintf("Filled %d cells", n)
+
+	case ToolLine, ToolBox, ToolMove:
+		if m.toolAnchor == nil {
+			anchor := m.cursor
+			m.toolAnchor = &anchor
+			m.statusMsg = fmt.Sprintf("%s: start at %d,%d - move and press d", toolNames[m.selectedTool], anchor.X, anchor.Y)
+			return
+		}
+		from := *m.toolAnchor
+		m.toolAnchor = nil
+		switch m.selectedTool {
+		case ToolLine:
+			m.beginEdit("line")
+			for _, p := range linePoints(from, m.cursor) {
+				m.setCell(p.X, p.Y, m.brushCell())
+			}
+			m.commitEdit()
+		case ToolBox:
+			m.beginEdit("box")
+			for p, r := range boxCells(from, m.cursor, borderSets[m.boxStyle]) {
+				cell := m.brushCell()
+				cell.Char = r
+				m.setCell(p.X, p.Y, cell)
+			}
+			m.commitEdit()
+		case ToolMove:
+			m.beginEdit("move")
+			if m.selection != nil {
+				m.moveSelection(m.cursor.X-from.X, m.cursor.Y-from.Y)
+			} else {
+				m.moveContent(m.cursor.X-from.X, m.cursor.Y-from.Y)
+			}
+			m.commitEdit()
+		}
+		m.statusMsg = ""
+
+	case ToolText:
+		if m.stampText == "" {
+			m.statusMsg = "text: set the stamp with :text <string>"
+			return
+		}
+		m.beginEdit("text")
+		m.stamp(m.cursor.X, m.cursor.Y, m.stampText)
+		m.commitEdit()
+
+	case ToolEyedropper:
+		cell, ok := m.compositeAt(m.cursor.X, m.cursor.Y)
+		if !ok {
+			return
+		}
+		m.fgChar = cell.Char
+		m.fgColor = cell.FG
+		m.bgColor = cell.BG
+		m.statusMsg = fmt.Sprintf("Picked '%c' fg %s bg %s", cell.Char, cell.FG, cell.BG)
+	}
+}
+
+// floodFill replaces the region connected to x,y whose cells match the
+// start cell on the configured attribute. Returns the number of cells changed.
+func (m *Model) floodFill(x, y int, fill Cell) int {
+	start, ok := m.cellAt(x, y)
+	if !ok {
+		return 0
+	}
+	grid := m.frames[m.currentFrame].Layers[m.currentLayer]
+	frame := m.frames[m.currentFrame]
+
+	matches := func(c Cell) bool {
+		switch m.fillMatch {
+		case MatchFG:
+			return c.FG == start.FG
+		case MatchBG:
+			return c.BG == start.BG
+		default:
+			return c.Char == start.Char
+		}
+	}
+
+	dirs := []Pos{{1, 0}, {-1, 0}, {0, 1}, {0, -1}}
+	if m.fillConnectivity == 8 {
+		dirs = append(dirs, Pos{1, 1}, Pos{1, -1}, Pos{-1, 1}, Pos{-1, -1})
+	}
+
+	visited := make([]bool, frame.Width*frame.Height)
+	queue := []Pos{{x, y}}
+	visited[y*frame.Width+x] = true
+	count := 0
+
+	for len(queue) > 0 {
+		p := queue[0]
+		queue = queue[1:]
+
+		if grid[p.Y][p.X] != fill {
+			count++
+		}
+		m.setCell(p.X, p.Y, fill)
+
+		for _, d := range dirs {
+			nx, ny := p.X+d.X, p.Y+d.Y
+			if nx < 0 || ny < 0 || nx >= frame.Width || ny >= frame.Height {
+				continue
+			}
+			if visited[ny*frame.Width+nx] {
+				continue
+			}
+			if !matches(grid[ny][nx]) {
+				continue
+			}
+			visited[ny*frame.Width+nx] = true
+			queue = append(queue, Pos{nx, ny})
+		}
+	}
+	return count
+}
+
+// linePoints returns the cells of a Bresenham line from a to b
+func linePoints(a, b Pos) []Pos {
+	dx := abs(b.X - a.X)
+	dy := -abs(b.Y - a.Y)
+	sx, sy := 1, 1
+	if a.X > b.X {
+		sx = -1
+	}
+	if a.Y > b.Y {
+		sy = -1
+	}
+	err := dx + dy
+
+	var points []Pos
+	x, y := a.X, a.Y
+	for {
+		points = append(points, Pos{x, y})
+		if x == b.X && y == b.Y {
+			break
+		}
+		e2 := 2 * err
+		if e2 >= dy {
+			err += dy
+			x += sx
+		}
+		if e2 <= dx {
+			err += dx
+			y += sy
+		}
+	}
+	return points
+}
+
+// boxCells returns the border characters of the rectangle spanned by a and b
+func boxCells(a, b Pos, set BorderSet) map[Pos]rune {
+	x0, x1 := min(a.X, b.X), max(a.X, b.X)
+	y0, y1 := min(a.Y, b.Y), max(a.Y, b.Y)
+	cells := make(map[Pos]rune)
+
+	// Degenerate boxes collapse to a straight line
+	if y0 == y1 {
+		for x := x0; x <= x1; x++ {
+			cells[Pos{x, y0}] = set.Horizontal
+		}
+		return cells
+	}
+	if x0 == x1 {
+		for y := y0; y <= y1; y++ {
+			cells[Pos{x0, y}] = set.Vertical
+		}
+		return cells
+	}
+
+	for x := x0 + 1; x < x1; x++ {
+		cells[Pos{x, y0}] = set.Horizontal
+		cells[Pos{x, y1}] = set.Horizontal
+	}
+	for y := y0 + 1; y < y1; y++ {
+		cells[Pos{x0, y}] = set.Vertical
+		cells[Pos{x1, y}] = set.Vertical
+	}
+	cells[Pos{x0, y0}] = set.TopLeft
+	cells[Pos{x1, y0}] = set.TopRight
+	cells[Pos{x0, y1}] = set.BottomLeft
+	cells[Pos{x1, y1}] = set.BottomRight
+	return cells
+}
+
+// stamp writes text at x,y using the current colors. "\n" starts a new
+// line below the starting column.
+func (m *Model) stamp(x, y int, text string) {
+	for dy, line := range strings.Split(text, "\n") {
+		for dx, r := range []rune(line) {
+			cell := m.brushCell()
+			cell.Char = r
+			m.setCell(x+dx, y+dy, cell)
+		}
+	}
+}
+
+// moveContent shifts the active layer of the current frame by dx,dy when nothing is
+// selected. Cells moved in from outside the canvas are blank.
+func (m *Model) moveContent(dx, dy int) {
+	if dx == 0 && dy == 0 {
+		return
+	}
+	frame := m.frames[m.currentFrame]
+	grid := frame.Layers[m.currentLayer]
+	src := make([][]Cell, frame.Height)
+	for y := range grid {
+		src[y] = append([]Cell(nil), grid[y]...)
+	}
+	blank := m.blankCell()
+	for y := 0; y < frame.Height; y++ {
+		for x := 0; x < frame.Width; x++ {
+			sx, sy := x-dx, y-dy
+			cell := blank
+			if sx >= 0 && sy >= 0 && sx < frame.Width && sy < frame.Height {
+				cell = src[sy][sx]
+			}
+			m.setCell(x, y, cell)
+		}
+	}
+}
+
+// toolPreview returns the characters the pending line/box would draw, so
+// the canvas can show them before the second point is placed
+func (m Model) toolPreview() map[Pos]rune {
+	if m.toolAnchor == nil {
+		return nil
+	}
+	switch m.selectedTool {
+	case ToolLine:
+		preview := make(map[Pos]rune)
+		for _, p := range linePoints(*m.toolAnchor, m.cursor) {
+			preview[p] = m.fgChar
+		}
+		return preview
+	case ToolBox:
+		return boxCells(*m.toolAnchor, m.cursor, borderSets[m.boxStyle])
+	}
+	return nil
+}
+
+// toolDetail describes the active tool settings for the status bar
+func (m Model) toolDetail() string {
+	switch m.selectedTool {
+	case ToolFill:
+		return fmt.Sprintf("fill %d/%s", m.fillConnectivity, fillMatchNames[m.fillMatch])
+	case ToolBox:
+		return "box " + borderSets[m.boxStyle].Name
+	case ToolText:
+		if m.stampText != "" {
+			return fmt.Sprintf("text %q", truncate(m.stampText, 12))
+		}
+	}
+	return toolNames[m.selectedTool]
+}
+
+// toolCommand handles :tool, :fill, :border and :text
+func (m *Model) toolCommand(parts []string) {
+	switch parts[0] {
+	case "tool":
+		if len(parts) < 2 {
+			m.statusMsg = "Usage: :tool " + strings.Join(toolNames, "|")
+			return
+		}
+		t, ok := toolByName(parts[1])
+		if !ok {
+			m.statusMsg = fmt.Sprintf("Unknown tool: %s", parts[1])
+			return
+		}
+		m.selectTool(t)
+
+	case "fill":
+		for _, arg := range parts[1:] {
+			switch arg {
+			case "4", "8":
+				m.fillConnectivity = int(arg[0] - '0')
+			default:
+				found := false
+				for i, name := range fillMatchNames {
+					if name == arg {
+						m.fillMatch = FillMatch(i)
+						found = true
+					}
+				}
+				if !found {
+					m.statusMsg = "Usage: :fill [4|8] [char|fg|bg]"
+					return
+				}
+			}
+		}
+		m.selectTool(ToolFill)
+		m.statusMsg = fmt.Sprintf("Fill: %d-connected, match %s", m.fillConnectivity, fillMatchNames[m.fillMatch])
+
+	case "border":
+		if len(parts) < 2 {
+			m.boxStyle = (m.boxStyle + 1) % len(borderSets)
+		} else {
+			found := false
+			for i, set := range borderSets {
+				if set.Name == parts[1] {
+					m.boxStyle = i
+					found = true
+				}
+			}
+			if !found {
+				names := make([]string, len(borderSets))
+				for i, set := range borderSets {
+					names[i] = set.Name
+				}
+				m.statusMsg = "Usage: :border " + strings.Join(names, "|")
+				return
+			}
+		}
+		m.selectTool(ToolBox)
+		m.statusMsg = "Border: " + borderSets[m.boxStyle].Name
+
+	case "text":
+		// Keep the raw text after the command, including spaces
+		text := strings.TrimPrefix(strings.TrimLeft(m.command, " "), "text")
+		if strings.HasPrefix(text, " ") {
+			text = text[1:]
+		}
+		m.stampText = strings.ReplaceAll(text, `\n`, "\n")
+		m.selectTool(ToolText)
+		m.statusMsg = fmt.Sprintf("Text stamp: %q", m.stampText)
+	}
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}